@@ -2,8 +2,11 @@ package animator
 
 import (
 	"fmt"
+	"math"
 	"tdgame/core"
 	"tdgame/graph"
+
+	"github.com/fogleman/gg"
 )
 
 type (
@@ -47,6 +50,12 @@ type (
 		locs []core.Location
 		t    *core.Ticker
 	}
+	// pathWalk lays the locations of a walk along a path a pixel at a time, x and y are where the walk is up to
+	pathWalk struct {
+		locs []core.Location
+		x, y float64
+		rot  int
+	}
 )
 
 const (
@@ -142,17 +151,58 @@ func NewPrecalculatedAnimator(k core.Kind, start core.Location, a Animator) Anim
 }
 
 func AnimatorFromLine(start, end core.Point, ticks int) *PrecalculatedAnimator {
-	flTicks := float64(ticks)
+	pa := &PrecalculatedAnimator{"lineanim", make([]core.Location, ticks), core.NewTicker(ticks)}
+	pa.Line(start, end, ticks)
+	return pa
+}
+
+// AnimatorFromCurve creates an animator along a quadratic bezier curve from start to end that bends toward
+// control, there is a location for every pixel of the curve's length and each location faces along the curve
+func AnimatorFromCurve(k core.Kind, start, control, end core.Point) *PrecalculatedAnimator {
+	at := func(t float64) (float64, float64) {
+		u := 1 - t
+		x := u*u*float64(start.X()) + 2*u*t*float64(control.X()) + t*t*float64(end.X())
+		y := u*u*float64(start.Y()) + 2*u*t*float64(control.Y()) + t*t*float64(end.Y())
+		return x, y
+	}
+	length, px, py := 0.0, float64(start.X()), float64(start.Y())
+	for i := 1; i <= 256; i++ {
+		x, y := at(float64(i) / 256)
+		length += math.Hypot(x-px, y-py)
+		px, py = x, y
+	}
+	ticks := core.MaxInt(1, int(length))
 	locs := make([]core.Location, ticks)
+	for i := range locs {
+		t := float64(i+1) / float64(ticks)
+		x, y := at(t)
+		// the derivative of the curve is the direction of travel, rotation 0 faces south
+		u := 1 - t
+		dx := 2*u*float64(control.X()-start.X()) + 2*t*float64(end.X()-control.X())
+		dy := 2*u*float64(control.Y()-start.Y()) + 2*t*float64(end.Y()-control.Y())
+		rot := (int(math.Round(gg.Degrees(math.Atan2(dy, dx)))) + 270) % 360
+		locs[i] = core.Loc(core.Pt(int(math.Round(x)), int(math.Round(y))), rot)
+	}
+	return &PrecalculatedAnimator{k, locs, core.NewTicker(ticks)}
+}
+
+// Line replaces the locations of the animator with a line and restarts it, the storage for the locations
+// is reused so only use it on animators that are not shared with copies
+func (pa *PrecalculatedAnimator) Line(start, end core.Point, ticks int) {
+	flTicks := float64(ticks)
+	if cap(pa.locs) < ticks {
+		pa.locs = make([]core.Location, ticks)
+	}
+	locs := pa.locs[:ticks]
 	xdif, ydif := float64(end.X()-start.X())/flTicks, float64(end.Y()-start.Y())/flTicks
 	curx, cury := float64(start.X()), float64(start.Y())
 	for i := 0; i < ticks; i++ {
 		curx, cury = curx+xdif, cury+ydif
 		locs[i] = core.Loc(core.Pt(int(curx), int(cury)), 0)
 	}
-	// fmt.Println(locs)
 	locs[len(locs)-1] = core.Loc(end, 0)
-	return &PrecalculatedAnimator{"lineanim", locs, core.NewTicker(ticks)}
+	pa.locs = locs
+	pa.t.Restart(ticks)
 }
 
 func (pa *PrecalculatedAnimator) Location(tick int) core.Location {
@@ -175,6 +225,21 @@ func (pa *PrecalculatedAnimator) LastLocation() (core.Location, int) {
 	return pa.locs[maxTicks-1], maxTicks - pa.t.Ticks()
 }
 
+// Progress is the fraction of the animation that has been played
+func (pa *PrecalculatedAnimator) Progress() float64 {
+	return float64(pa.t.Ticks()) / float64(pa.t.Max())
+}
+
+func (pa *PrecalculatedAnimator) Ticks() int {
+	return pa.t.Ticks()
+}
+
+// Seek moves the animator to a tick without animating anything
+func (pa *PrecalculatedAnimator) Seek(tick int) {
+	pa.t.Reset()
+	pa.t.TickBy(core.MaxInt(0, tick))
+}
+
 func (pa *PrecalculatedAnimator) Animate(a Animatable) {
 	if pa.Done() {
 		return
@@ -228,9 +293,15 @@ func (aa AnimatorAtlas) PreLoad(d *core.Declarations) {
 
 func (aa AnimatorAtlas) Load(spec core.Kinder, d *core.Declarations) {
 	g := d.Get(graph.GraphType).(graph.GraphAtlas).Graph("map").(graph.CachedImageGraph)
-	switch spec.(type) {
+	switch as := spec.(type) {
 	case *AnimatorSpec:
-		aa.CreatePathAnimator(g.StartLoc(), g.Path())
+		// the path tiles step across the tiles of the map whatever their size
+		for k, a := range TileAnimators(g.TileSize) {
+			aa.anims[k] = a
+		}
+		for _, s := range g.Segments() {
+			aa.CreatePathAnimator(SegmentAnimatorKind(as.Name, s.ID), s.StartLoc(), s.Kinds(), g)
+		}
 	default:
 		panic("variety of animator does not exist")
 	}
@@ -241,7 +312,6 @@ func (aa AnimatorAtlas) Animator(k core.Kind) Animator {
 }
 
 func (aa AnimatorAtlas) PrecalculatedAnimator(k core.Kind) *PrecalculatedAnimator {
-	fmt.Println(k)
 	return aa.Animator(k).(*PrecalculatedAnimator)
 }
 
@@ -260,61 +330,169 @@ func (aa AnimatorAtlas) SerialAnimatorFromPath(key core.Kind, p []core.Kind) Ani
 	return anim.Copy()
 }
 
-func (aa AnimatorAtlas) CreatePathAnimator(startLoc core.Location, path []core.Kind) {
-	loc := startLoc
-	sanim := aa.SerialAnimatorFromPath(core.Kind("path"), path)
-	aa.PutAnimator("prepath", NewPrecalculatedAnimator("prepath", loc, sanim))
+// SegmentAnimatorKind is the kind of the animator of k for segment i of the map, the first segment keeps k
+func SegmentAnimatorKind(k core.Kind, i int) core.Kind {
+	if i == 0 {
+		return k
+	}
+	return core.Kind(fmt.Sprintf("%s/%d", k, i))
+}
+
+func (aa AnimatorAtlas) CreatePathAnimator(k core.Kind, startLoc core.Location, path []core.Kind, g graph.Graph) {
+	aa.PutAnimator(k, NewPathAnimator(k, startLoc, path, g))
+}
+
+// NewPathAnimator precalculates the walk along the kinds of a path from start, the middle of the tile before the
+// first kind. Enemies follow a quarter circle through turns, turning as they go, and cross the corner tiles of
+// diagonal steps in a straight line. They turn in place on the last tile, the walk has to end in its middle.
+func NewPathAnimator(k core.Kind, start core.Location, kinds []core.Kind, g graph.Graph) *PrecalculatedAnimator {
+	width := g.Spec().TileSize
+	size := float64(width)
+	// cut reports whether kind i, on the tile whose top left corner is p, is a corner tile to cut across
+	cut := func(i int, p core.Point) bool {
+		nd := g.Node(p.TileIndex(width))
+		return i+2 < len(kinds) && kinds[i][0] != kinds[i][1] && nd != nil && nd.Diagonal()
+	}
+	w, at, edge := &pathWalk{make([]core.Location, 0), float64(start.X()), float64(start.Y()), start.Rot()}, start.Point, false
+	for i := 0; i < len(kinds); i++ {
+		entry, exit := core.StringToDirection(string(kinds[i][0])), core.StringToDirection(string(kinds[i][1]))
+		tile := at.Add(offset(entry, width))
+		if cut(i, tile) {
+			// from the middle of the tile before the corner to the middle of the one after it
+			at, i = tile.Add(offset(exit, width)), i+1
+			w.line(at, diagonal(entry, exit))
+			continue
+		}
+		if !edge {
+			w.line(at.Add(offset(entry, width/2)), entry.Rotation())
+		}
+		at, edge = tile, false
+		next := tile.Add(offset(exit, width))
+		switch {
+		case entry == exit:
+			w.line(at, entry.Rotation())
+		case cut(i+1, next):
+			// the diagonal turns while it cuts across
+			w.line(at, entry.Rotation())
+		case i+1 < len(kinds):
+			w.arc(entry, exit, size/2)
+			edge = true
+		default:
+			w.line(at, entry.Rotation())
+			w.turn(entry, exit)
+		}
+	}
+	return &PrecalculatedAnimator{k, w.locs, core.NewTicker(len(w.locs))}
+}
+
+// offset is dist pixels in direction d
+func offset(d core.Direction, dist int) core.Point {
+	return core.ZeroPt.Neighbor(d).Scale(dist)
+}
+
+// diagonal is the rotation of a location facing halfway between entry and exit
+func diagonal(entry, exit core.Direction) int {
+	if entry.Clockwise() == exit {
+		return core.Clockwise(entry.Rotation(), 45)
+	}
+	return core.CounterClockwise(entry.Rotation(), 45)
+}
+
+// line walks straight to p, turning toward rot at the speed enemies turn in place while it walks
+func (w *pathWalk) line(p core.Point, rot int) {
+	x, y := float64(p.X()), float64(p.Y())
+	ticks, from := core.MaxInt(1, int(math.Round(math.Hypot(x-w.x, y-w.y)))), w.rot
+	// the shortest way around to rot
+	diff := ((rot-from)%360+540)%360 - 180
+	for i := 1; i <= ticks; i++ {
+		f, turned := float64(i)/float64(ticks), core.MinInt(core.AbsInt(diff), i*90/RotationTime)
+		if diff < 0 {
+			turned = -turned
+		}
+		w.add(w.x+(x-w.x)*f, w.y+(y-w.y)*f, from+turned)
+	}
+	w.x, w.y, w.rot = x, y, from+diff
+}
+
+// arc walks a quarter circle of radius r from the edge of a tile entered moving in entry to the edge it leaves
+// through in exit, the circle is centered on the corner of the tile between them
+func (w *pathWalk) arc(entry, exit core.Direction, r float64) {
+	in, out := offset(entry, 1), offset(exit, 1)
+	cx, cy := w.x+float64(out.X())*r, w.y+float64(out.Y())*r
+	ticks, turn := core.MaxInt(1, int(math.Round(math.Pi/2*r))), -90
+	if entry.Clockwise() == exit {
+		turn = 90
+	}
+	for i := 1; i <= ticks; i++ {
+		f := float64(i) / float64(ticks)
+		sin, cos := math.Sincos(f * math.Pi / 2)
+		w.add(cx-float64(out.X())*r*cos+float64(in.X())*r*sin, cy-float64(out.Y())*r*cos+float64(in.Y())*r*sin,
+			entry.Rotation()+int(math.Round(f*float64(turn))))
+	}
+	w.x, w.y, w.rot = cx+float64(in.X())*r, cy+float64(in.Y())*r, entry.Rotation()+turn
+}
+
+// turn turns in place from facing entry to facing exit
+func (w *pathWalk) turn(entry, exit core.Direction) {
+	delta := 90 / RotationTime
+	if entry.Clockwise() != exit {
+		delta = -delta
+	}
+	for i := 1; i <= RotationTime; i++ {
+		w.add(w.x, w.y, entry.Rotation()+i*delta)
+	}
+	w.rot = entry.Rotation() + RotationTime*delta
+}
+
+func (w *pathWalk) add(x, y float64, rot int) {
+	w.locs = append(w.locs, core.Loc(core.Pt(int(math.Round(x)), int(math.Round(y))), rot))
 }
 
 var (
+	ClockwiseAnim = NewTileAnimator(RotationTime, core.CL, func(_ int, l core.Location) core.Location {
+		return l.Clockwise(3)
+	})
+	CounterClockwiseAnim = NewTileAnimator(RotationTime, core.CL, func(_ int, l core.Location) core.Location {
+		return l.CounterClockwise(3)
+	})
+	DefaultAnimatorAtlas = AnimatorAtlas{TileAnimators(core.DefaultTileSize)}
+)
+
+// TileAnimators are the animators of the path tiles for tiles size pixels wide, straight tiles move one pixel a
+// tick and turns rotate in place once they have crossed the tile
+func TileAnimators(size int) map[core.Kind]Animator {
 	// Straight anims (4 total)
 	//// Vertical Turns
-	NNAnim = NewTileAnimator(core.TileSizeInt, core.NN, func(_ int, l core.Location) core.Location {
+	nn := NewTileAnimator(size, core.NN, func(_ int, l core.Location) core.Location {
 		return l.North()
 	})
-	SSAnim = NewTileAnimator(core.TileSizeInt, core.SS, func(_ int, l core.Location) core.Location {
+	ss := NewTileAnimator(size, core.SS, func(_ int, l core.Location) core.Location {
 		return l.South()
 	})
 	//// Horizontal Turns
-	EEAnim = NewTileAnimator(core.TileSizeInt, core.EE, func(_ int, l core.Location) core.Location {
+	ee := NewTileAnimator(size, core.EE, func(_ int, l core.Location) core.Location {
 		return l.East()
 	})
-	WWAnim = NewTileAnimator(core.TileSizeInt, core.WW, func(_ int, l core.Location) core.Location {
+	ww := NewTileAnimator(size, core.WW, func(_ int, l core.Location) core.Location {
 		return l.West()
 	})
-	// Turn anims (10 total - primary rotation anims)
-	ClockwiseAnim = NewTileAnimator(RotationTime, core.CL, func(_ int, l core.Location) core.Location {
-		return l.Clockwise(3)
-	})
-	CounterClockwiseAnim = NewTileAnimator(RotationTime, core.CL, func(_ int, l core.Location) core.Location {
-		return l.CounterClockwise(3)
-	})
-	//// North Turns
-	NEAnim = NewSerialAnimator(core.NE, NNAnim, ClockwiseAnim)
-	NWAnim = NewSerialAnimator(core.NW, NNAnim, CounterClockwiseAnim)
-	//// South Turns
-	SEAnim = NewSerialAnimator(core.SE, SSAnim, CounterClockwiseAnim)
-	SWAnim = NewSerialAnimator(core.SW, SSAnim, ClockwiseAnim)
-	// East Turns
-	ENAnim = NewSerialAnimator(core.EN, EEAnim, CounterClockwiseAnim)
-	ESAnim = NewSerialAnimator(core.ES, EEAnim, ClockwiseAnim)
-	// WES Turns
-	WNAnim               = NewSerialAnimator(core.WN, WWAnim, ClockwiseAnim)
-	WSAnim               = NewSerialAnimator(core.WS, WWAnim, CounterClockwiseAnim)
-	DefaultAnimatorAtlas = AnimatorAtlas{
-		map[core.Kind]Animator{
-			core.NN: NNAnim,
-			core.SS: SSAnim,
-			core.EE: EEAnim,
-			core.WW: WWAnim,
-			core.NE: NEAnim,
-			core.NW: NWAnim,
-			core.SE: SEAnim,
-			core.SW: SWAnim,
-			core.EN: ENAnim,
-			core.ES: ESAnim,
-			core.WN: WNAnim,
-			core.WS: WSAnim,
-		},
+	return map[core.Kind]Animator{
+		core.NN: nn,
+		core.SS: ss,
+		core.EE: ee,
+		core.WW: ww,
+		// Turn anims (8 total)
+		//// North Turns
+		core.NE: NewSerialAnimator(core.NE, nn, ClockwiseAnim),
+		core.NW: NewSerialAnimator(core.NW, nn, CounterClockwiseAnim),
+		//// South Turns
+		core.SE: NewSerialAnimator(core.SE, ss, CounterClockwiseAnim),
+		core.SW: NewSerialAnimator(core.SW, ss, ClockwiseAnim),
+		//// East Turns
+		core.EN: NewSerialAnimator(core.EN, ee, CounterClockwiseAnim),
+		core.ES: NewSerialAnimator(core.ES, ee, ClockwiseAnim),
+		//// West Turns
+		core.WN: NewSerialAnimator(core.WN, ww, ClockwiseAnim),
+		core.WS: NewSerialAnimator(core.WS, ww, CounterClockwiseAnim),
 	}
-)
+}
@@ -2,6 +2,8 @@ package asset
 
 import (
 	"image"
+	"image/color"
+	"image/draw"
 	_ "image/png"
 	"regexp"
 	"tdgame/core"
@@ -17,38 +19,61 @@ type (
 		core.Processor
 		Size() core.Point
 		Offset() core.Point
+		// SetTileSize centers the asset in tiles size pixels wide, the default size unless it is set
+		SetTileSize(size int)
 		Draw(con *gg.Context, l core.Location)
 		Copy() Asset
 		Reset()
 	}
+	// StaticAsset and Sprite are drawn centered in the tile of their location, whatever the size of the tiles
 	StaticAsset struct {
-		offset core.Point
 		image.Image
+		tile int
 	}
 	Sprite struct {
 		image.Image
 		frames                   []image.Image
-		offset, size             core.Point
+		faded                    []image.Image // frames drawn see through
+		flashed                  []image.Image // white silhouettes of the frames
+		size                     core.Point
 		total, delay, cur, width int
 		t                        *core.Ticker
+		tile                     int
 	}
 )
 
+const (
+	// FadedAlpha is the opacity of faded sprite frames
+	FadedAlpha = 100
+	// FlashAlpha is the opacity of the silhouette drawn over a flashing sprite
+	FlashAlpha = 180
+)
+
 var (
 	SpriteRegEx   = regexp.MustCompile(`(\w+)_(\d+)_(\d+)`) //tag_delay_width
 	CenteredRegEx = regexp.MustCompile(`centered_(\w+)`)    //centered_tag
 )
 
 func (a *StaticAsset) Draw(con *gg.Context, l core.Location) {
-	sz := a.Bounds().Size()
+	sz, offset := a.Bounds().Size(), a.Offset()
 	con.Push()
-	con.RotateAbout(gg.Radians(float64(l.Rot())), float64(l.X()+sz.X+a.offset.X()), float64(l.Y()+sz.Y+a.offset.Y()))
-	con.DrawImage(a.Image, (l.X()-a.Bounds().Min.X)+a.offset.X(), (l.Y()-a.Bounds().Min.Y)+a.offset.X())
+	// rotate about the middle of the image where it is drawn in the tile
+	con.RotateAbout(gg.Radians(float64(l.Rot())), float64(l.X()+sz.X/2+offset.X()), float64(l.Y()+sz.Y/2+offset.Y()))
+	con.DrawImage(a.Image, (l.X()-a.Bounds().Min.X)+offset.X(), (l.Y()-a.Bounds().Min.Y)+offset.Y())
 	con.Pop()
 }
 
 func (a *StaticAsset) Offset() core.Point {
-	return a.offset
+	return centered(a.Size(), a.tile)
+}
+
+func (a *StaticAsset) SetTileSize(size int) {
+	a.tile = size
+}
+
+// centered is the offset that centers something of size in a tile tile pixels wide
+func centered(size core.Point, tile int) core.Point {
+	return core.Pt(tile, tile).Subtract(size).Reduce(2)
 }
 
 func (a *StaticAsset) Size() core.Point {
@@ -57,7 +82,7 @@ func (a *StaticAsset) Size() core.Point {
 }
 
 func (a *StaticAsset) Copy() Asset {
-	return &StaticAsset{a.offset, a.Image}
+	return &StaticAsset{a.Image, a.tile}
 }
 
 func (s *StaticAsset) Process(ticks int, con core.Context) bool { return false }
@@ -79,7 +104,11 @@ func (s *Sprite) Done() bool {
 }
 
 func (s *Sprite) Offset() core.Point {
-	return s.offset
+	return centered(s.size, s.tile)
+}
+
+func (s *Sprite) SetTileSize(size int) {
+	s.tile = size
 }
 
 func (s *Sprite) Size() core.Point {
@@ -90,13 +119,15 @@ func (s *Sprite) Copy() Asset {
 	return &Sprite{
 		s.Image,
 		s.frames,
-		s.offset,
+		s.faded,
+		s.flashed,
 		s.size,
 		s.total,
 		s.delay,
 		0,
 		s.width,
 		core.NewTicker(s.delay),
+		s.tile,
 	}
 }
 
@@ -117,12 +148,42 @@ func (s *Sprite) Reset() {
 	s.t.Reset()
 }
 
+// Fade creates a copy of img with its opacity scaled by alpha out of 255
+func Fade(img image.Image, alpha uint8) image.Image {
+	b := img.Bounds()
+	ret := image.NewRGBA(b)
+	draw.DrawMask(ret, b, img, b.Min, image.NewUniform(color.Alpha{alpha}), image.Point{}, draw.Over)
+	return ret
+}
+
+// Flash creates a white silhouette of img with an opacity of alpha out of 255
+func Flash(img image.Image, alpha uint8) image.Image {
+	b := img.Bounds()
+	ret := image.NewRGBA(b)
+	draw.DrawMask(ret, b, image.NewUniform(color.RGBA{alpha, alpha, alpha, alpha}), image.Point{}, img, b.Min, draw.Over)
+	return ret
+}
+
+// DrawFlash draws a white silhouette of the current frame of the sprite
+func (s *Sprite) DrawFlash(con *gg.Context, l core.Location) {
+	s.draw(con, l, s.flashed[s.cur])
+}
+
+// DrawFaded draws the current frame of the sprite see through
+func (s *Sprite) DrawFaded(con *gg.Context, l core.Location) {
+	s.draw(con, l, s.faded[s.cur])
+}
+
 func (s *Sprite) Draw(con *gg.Context, l core.Location) {
-	img := s.CurrentFrame()
+	s.draw(con, l, s.CurrentFrame())
+}
+
+func (s *Sprite) draw(con *gg.Context, l core.Location, img image.Image) {
 	con.Push()
 	// con.RotateAbout(gg.Radians(float64(l.Rot())), float64(l.X()+32), float64(l.Y()+32))
 	con.RotateAbout(gg.Radians(float64(l.Rot())), float64(l.X()+(s.size.X()/2)), float64(l.Y()+(s.size.Y()/2)))
-	con.DrawImage(img, (l.X()-img.Bounds().Min.X)+s.offset.X(), (l.Y()-img.Bounds().Min.Y)+s.offset.Y())
+	offset := s.Offset()
+	con.DrawImage(img, (l.X()-img.Bounds().Min.X)+offset.X(), (l.Y()-img.Bounds().Min.Y)+offset.Y())
 	con.Pop()
 }
 
@@ -78,22 +78,20 @@ func (aa AssetAtlas) Sprite(k core.Kind) *Sprite {
 }
 
 func NewStaticAsset(img image.Image) *StaticAsset {
-	sz := img.Bounds().Size()
-	x, y := (core.TileSizeInt-sz.X)/2, (core.TileSizeInt-sz.Y)/2
-	return &StaticAsset{core.Pt(x, y), img}
+	return &StaticAsset{img, core.DefaultTileSize}
 }
 
 func (spec *StaticSpec) AddAssets(aa AssetAtlas) {
 	for _, fil := range spec.Files {
 		name := core.Kind(strings.TrimSuffix(path.Base(fil), ".png"))
-		aa[name] = NewStaticAsset(readPNG(path.Join(spec.FilePath, spec.FilePrefix, fil)))
+		aa[name] = NewStaticAsset(ReadPNG(path.Join(spec.FilePath, spec.FilePrefix, fil)))
 	}
 }
 
 func (spec *SpriteSpec) AddAssets(aa AssetAtlas) {
 	for _, fil := range spec.Files {
 		name := core.Kind(strings.TrimSuffix(path.Base(fil.File), ".png"))
-		img := readPNG(path.Join(spec.FilePath, spec.FilePrefix, fil.File))
+		img := ReadPNG(path.Join(spec.FilePath, spec.FilePrefix, fil.File))
 		total := img.Bounds().Max.X / fil.Width
 		imgs := make([]image.Image, total)
 		y0, y1 := 0, img.Bounds().Max.Y
@@ -103,13 +101,16 @@ func (spec *SpriteSpec) AddAssets(aa AssetAtlas) {
 		}
 		t := core.NewTicker(fil.Delay)
 		size := core.Pt(fil.Width, img.Bounds().Max.Y)
-		offset := core.TileSizePt.Subtract(size).Reduce(2)
-		aa[name] = &Sprite{img, imgs, offset, size, total, fil.Delay, 0, fil.Width, t}
+		faded, flashed := make([]image.Image, total), make([]image.Image, total)
+		for i, frame := range imgs {
+			faded[i], flashed[i] = Fade(frame, FadedAlpha), Flash(frame, FlashAlpha)
+		}
+		aa[name] = &Sprite{img, imgs, faded, flashed, size, total, fil.Delay, 0, fil.Width, t, core.DefaultTileSize}
 	}
 }
 
 func (spec *MultiSpec) AddAssets(aa AssetAtlas) {
-	img := readPNG(path.Join(spec.FilePath, spec.File))
+	img := ReadPNG(path.Join(spec.FilePath, spec.File))
 	simg := img.(SubImager)
 	for _, desc := range spec.Assets {
 		partImg := simg.SubImage(image.Rect(desc.X, desc.Y, desc.X+spec.Width, desc.Y+spec.Height))
@@ -130,7 +131,7 @@ func (spec *MultiSpec) AddAssets(aa AssetAtlas) {
 
 // func (aa AssetAtlas) LoadAsset(file string) {
 // 	name, fullFile := strings.TrimSuffix(path.Base(file), path.Ext(file)), path.Join(aa.prefix, file)
-// 	img := readPNG(fullFile)
+// 	img := ReadPNG(fullFile)
 // 	if SpriteRegEx.MatchString(name) {
 // 		aa.HandleSprite(name, img)
 // 	} else if CenteredRegEx.MatchString(name) {
@@ -176,7 +177,8 @@ func (aa AssetAtlas) Load(spec core.Kinder, decs *core.Declarations) {
 	}
 }
 
-func readPNG(file string) image.Image {
+// ReadPNG decodes the image in file
+func ReadPNG(file string) image.Image {
 	data, err := os.ReadFile(file)
 	core.Check(err)
 	img, _, err := image.Decode(bytes.NewBuffer(data))
@@ -25,11 +25,30 @@ type (
 		s             *Sprite
 		el            *list.Element
 		started, done bool
+		release       func()
 	}
+	EffectPool = core.Pool[*SpriteEffect]
 )
 
 func NewSpriteEffect(l core.Location, s *Sprite) *SpriteEffect {
-	return &SpriteEffect{core.LocWrapper(l), s, nil, false, false}
+	return &SpriteEffect{core.LocWrapper(l), s, nil, false, false, nil}
+}
+
+// NewEffectPool creates a pool of copies of proto, each one returns itself to the pool when it is released
+func NewEffectPool(size int, proto *SpriteEffect) *EffectPool {
+	return core.NewPool(size, func() *SpriteEffect {
+		return proto.CopyAt(core.ZeroLoc).(*SpriteEffect)
+	}).SetShrink(core.ShrinkToPeak(size))
+}
+
+func (s *SpriteEffect) SetRelease(release func()) {
+	s.release = release
+}
+
+func (s *SpriteEffect) Release() {
+	if s.release != nil {
+		s.release()
+	}
 }
 
 func (s *SpriteEffect) Length() int {
@@ -38,12 +57,13 @@ func (s *SpriteEffect) Length() int {
 
 func (s *SpriteEffect) Process(ticks int, con core.Context) bool {
 	s.s.Process(ticks, con)
+	// the effect is done once its sprite loops back to the first frame
 	s.done = s.started && s.s.cur == 0 && s.s.t.Ticks() == 0
-	return false
+	s.started = true
+	return s.done
 }
 
 func (s *SpriteEffect) Draw(con *gg.Context) {
-	s.started = true
 	s.s.Draw(con, s.Location())
 }
 
@@ -62,6 +82,7 @@ func (s *SpriteEffect) Init() {
 
 func (s *SpriteEffect) Reset() {
 	s.s.Reset()
+	s.el = nil
 }
 
 func (s *SpriteEffect) SetElem(e *list.Element) {
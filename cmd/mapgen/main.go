@@ -0,0 +1,46 @@
+// mapgen renders generated maps to PNG so a seed can be looked at before it is shared
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"tdgame/asset"
+	"tdgame/core"
+	"tdgame/graph"
+	"time"
+
+	"github.com/fogleman/gg"
+)
+
+func main() {
+	spec := &graph.GraphSpec{Meta: core.Meta{Type: graph.GraphType, Variety: graph.GeneratedVariety, Name: "map"}}
+	flag.IntVar(&spec.Width, "width", 16, "width of the map in tiles")
+	flag.IntVar(&spec.Height, "height", 12, "height of the map in tiles")
+	flag.StringVar(&spec.Seed, "seed", "", "seed of the map, a new one is made up when empty")
+	flag.IntVar(&spec.Length.Min, "min", 0, "fewest tiles of the path")
+	flag.IntVar(&spec.Length.Max, "max", 0, "most tiles of the path, 0 for no limit")
+	flag.IntVar(&spec.Turns, "turns", 4, "number of turns of the path")
+	flag.Float64Var(&spec.Branchiness, "branchiness", .5, "share of the turns that get a branch around them")
+	flag.IntVar(&spec.TileSize, "tile", core.DefaultTileSize, "size in pixels of the tiles, the path assets must be drawn for it")
+	assets := flag.String("assets", "./0_gamedata/declarations/path.yaml", "declaration of the path assets")
+	out := flag.String("out", "", "png to write, map_<seed>.png when empty")
+	grid := flag.Bool("grid", false, "draw the grid over the map")
+	flag.Parse()
+	if spec.Seed == "" {
+		spec.Seed = strconv.FormatInt(time.Now().UnixNano()%(1<<32), 36)
+	}
+	if *out == "" {
+		*out = fmt.Sprintf("map_%s.png", spec.Seed)
+	}
+	decs := core.NewDeclarations()
+	decs.RegisterHandlers(asset.NewAssetAtlas()).AddFile(*assets).Load()
+	g := graph.GeneratedFromSpec(spec, decs.Get(asset.AssetType).(asset.AssetAtlas))
+	core.Grid = *grid
+	con := gg.NewContext(g.Size())
+	g.Draw(con)
+	core.Check(con.SavePNG(*out))
+	p := g.Path(0)
+	fmt.Printf("seed %s: %dx%d tiles, path from %s to %s with %d tiles, %d segments, written to %s\n",
+		spec.Seed, g.Width(), g.Height(), p.Start, p.End, len(p.Kinds()), len(g.Segments()), *out)
+}
@@ -0,0 +1,185 @@
+// mapstat reports how long enemies take to walk the paths of a map and how much of them towers can cover from
+// every buildable tile, with a heatmap of that coverage to judge whether a map is too easy
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image/color"
+	"math"
+	"sort"
+	"strings"
+	"tdgame/animator"
+	"tdgame/asset"
+	"tdgame/core"
+	"tdgame/graph"
+	"tdgame/td"
+
+	"github.com/fogleman/gg"
+)
+
+type (
+	// pathStats are the turns of a path, the tiles enemies walk through on it, the last one is past its end, and
+	// the walk along them
+	pathStats struct {
+		*graph.Path
+		tiles []core.Point
+		turns int
+		anim  *animator.PrecalculatedAnimator
+	}
+)
+
+// TPS is the ticks per second the game runs at
+const TPS = 32
+
+func main() {
+	decs := flag.String("decs", "./0_gamedata/declarations", "directory of the declarations to load")
+	name := flag.String("map", "map", "name of the graph declaration to analyze")
+	rng := core.Range{}
+	flag.IntVar(&rng.Min, "min", 0, "tiles from its center a tower cannot reach within")
+	flag.IntVar(&rng.Max, "max", 3, "tiles from its center a tower reaches")
+	out := flag.String("out", "", "heatmap png to write, heatmap_<map>.png when empty")
+	flag.Parse()
+	if *out == "" {
+		*out = fmt.Sprintf("heatmap_%s.png", *name)
+	}
+	d := core.NewDeclarations()
+	d.RegisterHandlers(
+		asset.NewAssetAtlas(),
+		graph.NewTerrainAtlas(),
+		graph.NewGraphAtlas(),
+		animator.DefaultAnimatorAtlas,
+		td.NewEnemyAtlas(),
+	).Ignore(td.TowerType).AddDir(*decs).Load()
+	g, ok := d.Get(graph.GraphType).(graph.GraphAtlas).Graph(core.Kind(*name)).(graph.CachedImageGraph)
+	if !ok {
+		panic(fmt.Sprintf("graph %s is not declared in %s", *name, *decs))
+	}
+	ea := d.Get(td.EnemyType).(*td.EnemyAtlas)
+	enemies := make([]*td.EnemySpec, 0, len(ea.Pools()))
+	for k := range ea.Pools() {
+		enemies = append(enemies, ea.Enemy(core.ZeroLoc, k).Spec())
+	}
+	sort.Slice(enemies, func(i, j int) bool { return enemies[i].Name < enemies[j].Name })
+
+	fmt.Printf("map %s: %dx%d tiles of %d pixels\n", *name, g.Width(), g.Height(), g.TileSize)
+	for i, p := range g.Paths() {
+		ps := walk(g, p)
+		_, length := ps.anim.LastLocation()
+		fmt.Printf("path %d from %s to %s: %d tiles, %d pixels to walk off the map, %d turns\n", i, p.Start, p.End, len(ps.tiles)-1, length, ps.turns)
+		for _, es := range enemies {
+			ticks := ps.traversal(g, es)
+			fmt.Printf("  %-10s speed %2d: %5d ticks, %5.1fs\n", es.Name, es.Speed, ticks, float64(ticks)/TPS)
+		}
+	}
+	cov, best := coverage(g, rng)
+	buildable, total := 0, 0
+	for _, row := range g.BasicGraph {
+		for _, nd := range row {
+			if nd.Buildable() {
+				buildable, total = buildable+1, total+cov[nd.Point]
+			}
+		}
+	}
+	fmt.Printf("%d buildable tiles, a tower reaching %d to %d tiles covers %d path tiles at most and %.1f on average\n",
+		buildable, rng.Min, rng.Max, best, float64(total)/math.Max(1, float64(buildable)))
+	fmt.Print(table(g, cov))
+	heatmap(g, cov, best, *out)
+	fmt.Printf("heatmap written to %s\n", *out)
+}
+
+// walk follows the kinds of a path, each kind moves enemies one tile in its entry direction and turns them to
+// its exit direction, the last one walks them off the map. The walk is laid out the way the game lays it out.
+func walk(g graph.CachedImageGraph, p *graph.Path) *pathStats {
+	anim := animator.NewPathAnimator("mapstat", p.StartLoc(), p.Kinds(), g)
+	ret, at := &pathStats{p, make([]core.Point, 0, len(p.Kinds())), 0, anim}, p.InitialPoint()
+	for _, k := range p.Kinds() {
+		at = at.Neighbor(core.StringToDirection(string(k[0])))
+		ret.tiles = append(ret.tiles, at)
+		if k[0] != k[1] {
+			ret.turns++
+		}
+	}
+	return ret
+}
+
+// traversal is how many ticks an enemy takes to walk the path, it walks the locations of the path
+// Speed at a time, slowed or sped up by the terrain it is on, the way the game moves it. Flying enemies cross
+// from the start of the path to its end as the crow flies.
+func (ps *pathStats) traversal(g graph.CachedImageGraph, es *td.EnemySpec) int {
+	if es.Variety == td.FlyingVariety {
+		from, to := ps.StartLoc().Center(g.TileSize), ps.EndLoc().Center(g.TileSize)
+		return int(math.Ceil(math.Sqrt(float64(from.DistanceSquared(to))) / float64(es.Speed)))
+	}
+	_, length := ps.anim.LastLocation()
+	ret, progress, loc := 0, 0, ps.StartLoc()
+	for at := 0; at < length; ret++ {
+		progress += es.Speed * g.ModifiersAt(loc.Center(g.TileSize)).Speed
+		loc, at, progress = ps.anim.Location(at), at+progress/100, progress%100
+	}
+	return ret
+}
+
+// coverage is how many path tiles have their center in reach of a tower on each buildable tile, the range
+// stretched by the terrain of the tile like a beam tower's
+func coverage(g graph.CachedImageGraph, rng core.Range) (map[core.Point]int, int) {
+	ret, best := make(map[core.Point]int), 0
+	for _, row := range g.BasicGraph {
+		for _, nd := range row {
+			if !nd.Buildable() {
+				continue
+			}
+			center := nd.Point.Scale(g.TileSize).Center(g.TileSize)
+			min, max := rng.Min*g.TileSize, rng.Max*g.TileSize*(100+nd.Modifiers().Range)/100
+			for _, pnd := range g.NodesWithin(center, max) {
+				dist := pnd.Point.Scale(g.TileSize).Center(g.TileSize).DistanceSquared(center)
+				if !pnd.IsBlank() && dist >= core.Square(min) && dist <= core.Square(max) {
+					ret[nd.Point]++
+				}
+			}
+			best = core.MaxInt(best, ret[nd.Point])
+		}
+	}
+	return ret, best
+}
+
+// table lays the coverage out like the map, path tiles are = and tiles that cannot be built on are #
+func table(g graph.CachedImageGraph, cov map[core.Point]int) string {
+	sb := strings.Builder{}
+	for _, row := range g.BasicGraph {
+		for _, nd := range row {
+			switch {
+			case !nd.IsBlank():
+				sb.WriteString("  =")
+			case !nd.Buildable():
+				sb.WriteString("  #")
+			default:
+				sb.WriteString(fmt.Sprintf("%3d", cov[nd.Point]))
+			}
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// heatmap draws the map with every buildable tile shaded from blue for no coverage to red for the best coverage
+func heatmap(g graph.CachedImageGraph, cov map[core.Point]int, best int, file string) {
+	core.Grid = false
+	con := gg.NewContext(g.Size())
+	g.Draw(con)
+	for _, row := range g.BasicGraph {
+		for _, nd := range row {
+			if !nd.Buildable() {
+				continue
+			}
+			heat := float64(cov[nd.Point]) / math.Max(1, float64(best))
+			con.SetColor(color.NRGBA{uint8(255 * heat), 0, uint8(255 * (1 - heat)), 150})
+			con.DrawRectangle(nd.Point.Coordinates(g.TileSize))
+			con.Fill()
+			c := nd.Point.Scale(g.TileSize).Center(g.TileSize)
+			con.SetColor(color.White)
+			con.DrawStringAnchored(fmt.Sprint(cov[nd.Point]), float64(c.X()), float64(c.Y()), .5, .5)
+		}
+	}
+	core.Check(con.SavePNG(file))
+}
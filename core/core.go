@@ -56,17 +56,23 @@ const (
 	ES Kind = "ES"
 	WN Kind = "WN"
 	WS Kind = "WS"
+	// Junctions, T junctions are named by their closed side
+	TN Kind = "TN"
+	TE Kind = "TE"
+	TS Kind = "TS"
+	TW Kind = "TW"
+	XX Kind = "XX" // crossroads
 )
 
+// DefaultTileSize is the size in pixels of the tiles of maps that do not declare one
+const DefaultTileSize = 64
+
 var (
-	TileSizeInt int     = 64
-	TileSize    float64 = 64
-	ZeroPt              = Point{0, 0}
-	ZeroLoc             = Loc(ZeroPt, 0)
-	TileSizePt          = Pt(TileSizeInt, TileSizeInt)
-	Grid                = true
-	Directions          = []Direction{N, E, S, W}
-	PointRegEx          = regexp.MustCompile(`(\d+),(\d+)`)
+	ZeroPt     = Point{0, 0}
+	ZeroLoc    = Loc(ZeroPt, 0)
+	Grid       = true
+	Directions = []Direction{N, E, S, W}
+	PointRegEx = regexp.MustCompile(`(\d+),(\d+)`)
 )
 
 func North(y int) int {
@@ -109,8 +115,9 @@ func StringToDirection(s string) Direction {
 	}
 }
 
-func TileIndexToCoordinate(idx int) float64 {
-	return (float64(idx) * TileSize)
+// TileIndexToCoordinate is the pixel coordinate of the edge of tile idx for tiles size pixels wide
+func TileIndexToCoordinate(idx, size int) float64 {
+	return float64(idx * size)
 }
 
 func (d Direction) Opposite() Direction {
@@ -128,6 +135,22 @@ func (d Direction) Opposite() Direction {
 	}
 }
 
+// Clockwise is the direction a quarter turn clockwise from d
+func (d Direction) Clockwise() Direction {
+	switch d {
+	case N:
+		return E
+	case E:
+		return S
+	case S:
+		return W
+	case W:
+		return N
+	default:
+		panic("cannot turn an unknown direction")
+	}
+}
+
 func (d Direction) String() string {
 	switch d {
 	case N:
@@ -143,6 +166,42 @@ func (d Direction) String() string {
 	}
 }
 
+// Rotation is the rotation of a location facing d, rotation 0 faces south
+func (d Direction) Rotation() int {
+	switch d {
+	case N:
+		return 180
+	case E:
+		return CounterClockwise(0, 90)
+	case S:
+		return 0
+	case W:
+		return Clockwise(0, 90)
+	default:
+		panic("cannot rotate toward an unknown direction")
+	}
+}
+
+// JunctionKind is the kind of a tile that is open on three or four sides
+func JunctionKind(open []Direction) Kind {
+	if len(open) == 4 {
+		return XX
+	}
+	if len(open) != 3 {
+		panic("junction must be open on three or four sides")
+	}
+	for _, d := range Directions {
+		closed := true
+		for _, o := range open {
+			closed = closed && o != d
+		}
+		if closed {
+			return Kind("T" + d.String())
+		}
+	}
+	panic("junction must be open on three different sides")
+}
+
 func DirectionsToKind(entry, exit Direction) Kind {
 	if entry.Opposite() == exit {
 		panic("entry and exit cannot be opposite in Kind")
@@ -197,11 +256,12 @@ func (p Point) XY() (int, int) {
 	return p.x, p.y
 }
 
-func (p Point) Coordinates() (float64, float64, float64, float64) {
-	return TileIndexToCoordinate(p.x),
-		TileIndexToCoordinate(p.y),
-		TileSize,
-		TileSize
+// Coordinates are the x, y, width and height in pixels of tile p for tiles size pixels wide
+func (p Point) Coordinates(size int) (float64, float64, float64, float64) {
+	return TileIndexToCoordinate(p.x, size),
+		TileIndexToCoordinate(p.y, size),
+		float64(size),
+		float64(size)
 }
 
 func (p Point) Near(o Point, maxDist int) bool {
@@ -237,8 +297,14 @@ func (p Point) String() string {
 	return fmt.Sprintf("(%d,%d)", p.x, p.y)
 }
 
-func (p Point) TileIndex() Point {
-	return p.Reduce(TileSizeInt)
+// Center is the middle of the box size pixels wide whose top left corner is p.
+func (p Point) Center(size int) Point {
+	return p.Add(Pt(size/2, size/2))
+}
+
+// TileIndex is the tile p is on for tiles size pixels wide
+func (p Point) TileIndex(size int) Point {
+	return p.Reduce(size)
 }
 
 func LocWrapper(l Location) *LocationWrapper {
@@ -0,0 +1,6 @@
+//go:build debug
+
+package core
+
+// Debug is set for builds with the debug tag, it turns on pool leak detection and the pool overlay
+const Debug = true
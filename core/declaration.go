@@ -1,6 +1,7 @@
 package core
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"path"
@@ -46,6 +47,7 @@ type (
 	Declarations struct {
 		specs    []Spec
 		handlers map[Kind]DeclarationHandler
+		ignored  map[Kind]bool
 	}
 )
 
@@ -59,8 +61,17 @@ func StructToYaml(in interface{}) string {
 	return string(out)
 }
 
+// HandlePreMeta decodes a declaration into the spec of its handler, declarations of an ignored type are skipped
+// and any other type without a handler is an error
 func (d *Declarations) HandlePreMeta(pm *PreMeta) {
-	handler := d.handlers[pm.Kind()]
+	handler, ok := d.handlers[pm.Kind()]
+	if !ok && d.ignored[pm.Kind()] {
+		log.Printf("Skipping %s, %s declarations are ignored", pm.Meta.Name, pm.Kind())
+		return
+	}
+	if !ok {
+		panic(fmt.Sprintf("declaration %s in %s has type %s, which has no handler", pm.Meta.Name, pm.FilePath, pm.Kind()))
+	}
 	if spec, prior := handler.Match(pm); spec != nil {
 		val := reflect.ValueOf(spec)
 		if val.Kind() != reflect.Ptr {
@@ -80,10 +91,18 @@ func (d *Declarations) HandlePreMeta(pm *PreMeta) {
 }
 
 func NewDeclarations() *Declarations {
-	ret := &Declarations{make([]Spec, 0), make(map[Kind]DeclarationHandler)}
+	ret := &Declarations{make([]Spec, 0), make(map[Kind]DeclarationHandler), make(map[Kind]bool)}
 	return ret
 }
 
+// Ignore skips declarations of the types, for tools that load only the parts of the game they need
+func (d *Declarations) Ignore(types ...Kind) *Declarations {
+	for _, k := range types {
+		d.ignored[k] = true
+	}
+	return d
+}
+
 func (d *Declarations) RegisterHandlers(dhs ...DeclarationHandler) *Declarations {
 	for _, dh := range dhs {
 		d.handlers[dh.Type()] = dh
@@ -118,8 +137,8 @@ func (d *Declarations) Load() *Declarations {
 	for _, handler := range d.handlers {
 		handler.PreLoad(d)
 	}
-	// sort specs by priority
-	sort.Slice(d.specs, func(i, j int) bool {
+	// sort specs by priority, declarations of the same kind and name added later replace earlier ones
+	sort.SliceStable(d.specs, func(i, j int) bool {
 		return d.specs[i].priority < d.specs[j].priority
 	})
 	// load each handler in order of priority
@@ -32,6 +32,7 @@ type (
 		Processor
 		Drawer
 	}
+	Attributes    map[ContextKey]ContextValue
 	GameObjectSet map[GameObject]Flag
 	GameObjects   interface {
 		GameObject
@@ -48,10 +49,37 @@ const (
 	ProjectileLayer
 	TowerLayer
 	EnemyLayer
+	AirLayer
 	EffectLayer
 	NumberOfLayers
 )
 
+// Attribute is safe to call on a context that was created without an Attributer
+func (c *BasicContext) Attribute(k ContextKey) ContextValue {
+	if c.Attributer == nil {
+		return nil
+	}
+	return c.Attributer.Attribute(k)
+}
+
+func (c *BasicContext) SetAttribute(k ContextKey, v ContextValue) {
+	if c.Attributer != nil {
+		c.Attributer.SetAttribute(k, v)
+	}
+}
+
+func NewAttributes() Attributes {
+	return make(Attributes)
+}
+
+func (a Attributes) Attribute(k ContextKey) ContextValue {
+	return a[k]
+}
+
+func (a Attributes) SetAttribute(k ContextKey, v ContextValue) {
+	a[k] = v
+}
+
 func (GameObjectNoop) Draw(con *gg.Context)                {}
 func (GameObjectNoop) Process(ticks int, con Context) bool { return false }
 
@@ -74,6 +102,9 @@ func (dos GameObjectSet) Process(ticks int, con Context) bool {
 	for d := range dos {
 		if d.Process(ticks, con) {
 			dos.Remove(d)
+			if r, ok := d.(Releaser); ok {
+				r.Release()
+			}
 		}
 	}
 	// GameObjectSet is never done, it should not be cleaned up until the end of the game
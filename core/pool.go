@@ -1,82 +1,268 @@
 package core
 
+import (
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/fogleman/gg"
+)
+
 type (
-	List     []PoolItem
 	PoolItem interface {
 		Active() bool
 		Init()
 		Reset()
 	}
-	Creator func() PoolItem
-	Pool    interface {
-		Item() PoolItem
-		Return(i PoolItem)
+	// Releaser is implemented by objects that go back to a pool once they are removed from their layer
+	Releaser interface {
+		Release()
+	}
+	// Releasable items are given a function by their pool that returns them to it
+	Releasable interface {
+		Releaser
+		SetRelease(release func())
+	}
+	// GrowthPolicy decides how many items to add to an empty pool that has handed out size items
+	GrowthPolicy func(size int) int
+	// ShrinkPolicy decides the size a pool should shrink to, free items past it are dropped
+	ShrinkPolicy func(s PoolStats) int
+	// PoolStats instruments a pool, a miss is an Item call that found the pool empty and had to grow it and a
+	// reject is an Item call that could not be served because the pool was at its max size
+	PoolStats struct {
+		Size, InUse, HighWater, Peak          int
+		Hits, Misses, Growths, Frees, Rejects int
+		Shrinks, Leaks                        int
+	}
+	Monitored interface {
+		Processor
+		Stats() PoolStats
+	}
+	// Pool hands out items created by c and takes them back once they are done, it grows when it is empty
+	// unless it has reached its max size
+	Pool[T PoolItem] struct {
+		c      func() T
+		items  []T
+		stats  PoolStats
+		min    int
+		max    int // 0 for no max
+		leak   int // ticks an item can be out before it is reported as a leak, 0 or less never reports
+		growth GrowthPolicy
+		shrink ShrinkPolicy
+		// out is the tick each item was taken from the pool at, only tracked in debug builds
+		out  map[PoolItem]int
+		tick int
 	}
-	BasicPool struct {
-		c Creator
-		l *List
+	// PoolMonitor processes a set of pools every tick so that they shrink and report leaks, it draws their stats
+	// when it is visible
+	PoolMonitor struct {
+		pools   map[Kind]Monitored
+		Visible bool
 	}
 )
 
-func NewList(size int, f func() PoolItem) *List {
-	items := make(List, size)
-	for i := 0; i < size; i++ {
-		items[i] = f()
+const (
+	// ShrinkInterval is the number of ticks between a pool checking whether it should shrink
+	ShrinkInterval = 256
+	// LeakTicks is the default number of ticks an item can be out of its pool before it is reported as a leak
+	LeakTicks = 60 * 32
+)
+
+// DoubleGrowth doubles the size of the pool
+func DoubleGrowth(size int) int {
+	return MaxInt(1, size)
+}
+
+// LinearGrowth grows the pool by n items at a time
+func LinearGrowth(n int) GrowthPolicy {
+	return func(int) int {
+		return MaxInt(1, n)
 	}
-	return &items
 }
 
-func (l *List) Len() int {
-	return len(*l)
+// NeverShrink keeps every item the pool has ever created
+func NeverShrink(s PoolStats) int {
+	return s.Size
 }
 
-func (l *List) Empty() bool {
-	return len(*l) == 0
+// ShrinkToPeak drops free items past the most items in use since the last shrink, keeping at least min
+func ShrinkToPeak(min int) ShrinkPolicy {
+	return func(s PoolStats) int {
+		return MaxInt(min, s.Peak)
+	}
 }
 
-func (l *List) Item() PoolItem {
-	idx := len(*l) - 1
-	ret := (*l)[idx]
-	*l = (*l)[0:idx]
-	return ret
+// NewPool creates a pool of size items that doubles when it is empty and never shrinks
+func NewPool[T PoolItem](size int, c func() T) *Pool[T] {
+	p := &Pool[T]{c: c, items: make([]T, 0, size), min: size, leak: LeakTicks, growth: DoubleGrowth, shrink: NeverShrink}
+	if Debug {
+		p.out = make(map[PoolItem]int)
+	}
+	p.add(size)
+	return p
 }
 
-func (l *List) Return(i PoolItem) {
-	*l = append(*l, i)
+func (p *Pool[T]) SetMax(max int) *Pool[T] {
+	p.max = max
+	return p
 }
 
-func (l *List) Double(f func() PoolItem) {
-	listCap, listLen := cap(*l), len(*l)
-	newList := make(List, listCap*2)
-	copy(newList, *l)
-	for i := 0; i < listCap; i++ {
-		newList[listLen+i] = f()
-	}
-	*l = newList
+// SetLeakTicks sets how long an item can be out of the pool before it is reported as a leak, items that live
+// as long as they like, such as bosses, should use 0 so that they are never reported
+func (p *Pool[T]) SetLeakTicks(ticks int) *Pool[T] {
+	p.leak = ticks
+	return p
 }
 
-func (l *List) Shrink() {
-	newList := make(List, len(*l))
-	copy(newList, *l)
-	*l = newList
+func (p *Pool[T]) SetGrowth(g GrowthPolicy) *Pool[T] {
+	p.growth = g
+	return p
 }
 
-func NewPool(size int, c Creator) *BasicPool {
-	p := &BasicPool{c, NewList(size, c)}
+func (p *Pool[T]) SetShrink(s ShrinkPolicy) *Pool[T] {
+	p.shrink = s
 	return p
 }
 
-func (p *BasicPool) Item() PoolItem {
-	if p.l.Empty() {
-		p.l.Double(p.c)
+// add creates n new items, items that can be released are given a function that returns them to the pool
+func (p *Pool[T]) add(n int) int {
+	if p.max > 0 {
+		n = MinInt(n, p.max-p.stats.Size)
+	}
+	for i := 0; i < n; i++ {
+		item := p.c()
+		if r, ok := PoolItem(item).(Releasable); ok {
+			r.SetRelease(func() { p.Return(item) })
+		}
+		p.items = append(p.items, item)
+	}
+	n = MaxInt(0, n)
+	p.stats.Size += n
+	return n
+}
+
+// TryItem takes an item from the pool, it reports false when the pool is empty and already at its max size so
+// that callers can wait for an item to be returned
+func (p *Pool[T]) TryItem() (T, bool) {
+	if len(p.items) == 0 {
+		if p.add(p.growth(p.stats.Size)) == 0 {
+			p.stats.Rejects++
+			var zero T
+			return zero, false
+		}
+		p.stats.Misses++
+		p.stats.Growths++
+	} else {
+		p.stats.Hits++
+	}
+	idx := len(p.items) - 1
+	ret := p.items[idx]
+	var zero T
+	p.items[idx] = zero
+	p.items = p.items[:idx]
+	p.stats.InUse++
+	p.stats.HighWater = MaxInt(p.stats.HighWater, p.stats.InUse)
+	p.stats.Peak = MaxInt(p.stats.Peak, p.stats.InUse)
+	if p.out != nil {
+		p.out[ret] = p.tick
 	}
-	item := p.l.Item()
-	ret := item
 	ret.Init()
+	return ret, true
+}
+
+// Item takes an item from the pool, it panics if the pool is at its max size
+func (p *Pool[T]) Item() T {
+	ret, ok := p.TryItem()
+	if !ok {
+		panic("pool is at its max size")
+	}
 	return ret
 }
 
-func (p *BasicPool) Return(i PoolItem) {
+func (p *Pool[T]) Return(i T) {
+	if p.out != nil {
+		if _, ok := p.out[i]; !ok {
+			panic(fmt.Sprintf("%T returned to pool twice", i))
+		}
+		delete(p.out, i)
+	}
+	p.stats.InUse--
+	p.stats.Frees++
 	i.Reset()
-	p.l.Return(i)
+	p.items = append(p.items, i)
+}
+
+// Process shrinks the pool every ShrinkInterval ticks and in debug builds reports items that have been out of
+// the pool for longer than its leak ticks
+func (p *Pool[T]) Process(ticks int, con Context) bool {
+	p.tick = ticks
+	if ticks%ShrinkInterval == 0 {
+		p.Shrink()
+	}
+	if p.out != nil && p.leak > 0 {
+		for item, at := range p.out {
+			if ticks-at == p.leak {
+				p.stats.Leaks++
+				log.Printf("%T has not been returned to its pool for %d ticks", item, p.leak)
+			}
+		}
+	}
+	return false
+}
+
+// Shrink drops free items until the pool is the size its shrink policy wants
+func (p *Pool[T]) Shrink() {
+	target := MaxInt(p.min, p.shrink(p.stats))
+	p.stats.Peak = p.stats.InUse
+	drop := MinInt(len(p.items), p.stats.Size-target)
+	if drop <= 0 {
+		return
+	}
+	items := make([]T, len(p.items)-drop, cap(p.items)-drop)
+	copy(items, p.items)
+	p.items = items
+	p.stats.Size -= drop
+	p.stats.Shrinks++
+}
+
+func (p *Pool[T]) Draw(con *gg.Context) {}
+
+func (p *Pool[T]) Stats() PoolStats {
+	return p.stats
+}
+
+func NewPoolMonitor() *PoolMonitor {
+	return &PoolMonitor{make(map[Kind]Monitored), Debug}
+}
+
+func (pm *PoolMonitor) Add(k Kind, p Monitored) {
+	pm.pools[k] = p
+}
+
+func (pm *PoolMonitor) Stats(k Kind) PoolStats {
+	return pm.pools[k].Stats()
+}
+
+func (pm *PoolMonitor) Process(ticks int, con Context) bool {
+	for _, p := range pm.pools {
+		p.Process(ticks, con)
+	}
+	return false
+}
+
+func (pm *PoolMonitor) Draw(con *gg.Context) {
+	if !pm.Visible {
+		return
+	}
+	ks := make([]string, 0, len(pm.pools))
+	for k := range pm.pools {
+		ks = append(ks, string(k))
+	}
+	sort.Strings(ks)
+	con.SetRGBA(1, 1, 1, .8)
+	for i, k := range ks {
+		s := pm.pools[Kind(k)].Stats()
+		line := fmt.Sprintf("%s %d/%d high %d miss %d reject %d leak %d", k, s.InUse, s.Size, s.HighWater, s.Misses, s.Rejects, s.Leaks)
+		con.DrawString(line, 4, float64(16*(i+2)))
+	}
 }
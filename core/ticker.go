@@ -30,6 +30,11 @@ func (t *Ticker) Reset() {
 	t.cur = 0
 }
 
+// Restart resets the ticker with a new max
+func (t *Ticker) Restart(max int) {
+	t.max, t.cur = max, 0
+}
+
 func NewTicker(cur int) *Ticker {
 	return &Ticker{cur, 0}
 }
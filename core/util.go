@@ -26,6 +26,13 @@ func MinInt(a, b int) int {
 	return b
 }
 
+func AbsInt(a int) int {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
 func Square(a int) int {
 	return a * a
 }
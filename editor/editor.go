@@ -0,0 +1,282 @@
+package editor
+
+import (
+	"fmt"
+	"image/color"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strconv"
+	"tdgame/asset"
+	"tdgame/core"
+	"tdgame/graph"
+
+	"github.com/fogleman/gg"
+)
+
+type (
+	// change is a tile painted over
+	change struct {
+		p             core.Point
+		before, after graph.GridTile
+	}
+	// stroke is every change made while a mouse button was held, they are undone and redone together
+	stroke []change
+	// Map is a grid map being edited, it is checked every time it changes so problems show up as they are made
+	Map struct {
+		spec       *graph.GraphSpec
+		file       string
+		tiles      [][]graph.GridTile
+		aa         asset.AssetAtlas
+		undo, redo []stroke
+		cur        stroke
+		painting   bool
+		preview    graph.BasicGraph
+		problem    string
+		bad        *core.Point // the tile of the problem, nil when the problem is not with a single tile
+	}
+)
+
+const (
+	// DefaultWidth and DefaultHeight are the size of new maps in tiles
+	DefaultWidth  = 16
+	DefaultHeight = 12
+)
+
+var (
+	// tileRegEx finds the tile in the problems reported by grid maps
+	tileRegEx = regexp.MustCompile(`row (\d+) column (\d+)`)
+	// markerColors are the colors of the rings drawn around spawns, exits and towers
+	markerColors = map[core.Kind]color.Color{
+		graph.SpawnTile: color.RGBA{40, 200, 60, 255},
+		graph.ExitTile:  color.RGBA{220, 40, 40, 255},
+		graph.TowerTile: color.RGBA{40, 40, 40, 255},
+	}
+)
+
+// Open edits the grid map in file, a new map of width by height tiles of grass is made when there is no file.
+// The spec gives the map its terrains and the name it is checked under.
+func Open(spec *graph.GraphSpec, file string, width, height int, aa asset.AssetAtlas) *Map {
+	spec.File = file
+	m := &Map{spec: spec, file: file, aa: aa}
+	data, err := ioutil.ReadFile(file)
+	switch {
+	case os.IsNotExist(err):
+		m.tiles = make([][]graph.GridTile, height)
+		for y := range m.tiles {
+			m.tiles[y] = make([]graph.GridTile, width)
+			for x := range m.tiles[y] {
+				m.tiles[y][x] = graph.GridTile{Meaning: graph.Grass}
+			}
+		}
+	default:
+		core.Check(err)
+		m.tiles = graph.ReadGrid(spec, string(data))
+		if len(m.tiles) == 0 {
+			panic(fmt.Sprintf("map %s has no %s to edit", file, graph.GridHeader))
+		}
+	}
+	m.check()
+	return m
+}
+
+func (m *Map) Width() int {
+	return len(m.tiles[0])
+}
+
+func (m *Map) Height() int {
+	return len(m.tiles)
+}
+
+func (m *Map) Contains(p core.Point) bool {
+	return p.X() >= 0 && p.Y() >= 0 && p.X() < m.Width() && p.Y() < m.Height()
+}
+
+// Tile is what is painted on the tile at p
+func (m *Map) Tile(p core.Point) graph.GridTile {
+	return m.tiles[p.Y()][p.X()]
+}
+
+// Begin starts a stroke, every tile painted until End is undone at once
+func (m *Map) Begin() {
+	m.painting, m.cur = true, make(stroke, 0)
+}
+
+// End finishes the stroke and checks the map
+func (m *Map) End() {
+	if !m.painting {
+		return
+	}
+	m.painting = false
+	if len(m.cur) == 0 {
+		return
+	}
+	m.undo, m.redo = append(m.undo, m.cur), nil
+	m.check()
+}
+
+// Painting reports whether a stroke has begun
+func (m *Map) Painting() bool {
+	return m.painting
+}
+
+// Paint paints a brush on the tile at p. Terrain painted on a path tile goes under its road and path painted on
+// terrain other than grass keeps it under the road, towers are replaced by whatever is painted over them.
+func (m *Map) Paint(p core.Point, brush graph.GridTile) {
+	if !m.Contains(p) {
+		return
+	}
+	before, after := m.Tile(p), brush
+	switch {
+	case brush.IsPath() && before.Terrain() != graph.Grass:
+		after.Arg = before.Terrain()
+	case brush.Meaning != graph.TowerTile && !brush.IsPath() && before.IsPath():
+		after.Meaning, after.Arg = before.Meaning, brush.Meaning
+		if brush.Meaning == graph.Grass {
+			after.Arg = ""
+		}
+	}
+	m.apply(p, after)
+}
+
+// Erase turns the tile at p back into grass
+func (m *Map) Erase(p core.Point) {
+	if m.Contains(p) {
+		m.apply(p, graph.GridTile{Meaning: graph.Grass})
+	}
+}
+
+// apply changes the tile at p as part of the stroke or on its own when no stroke has begun
+func (m *Map) apply(p core.Point, t graph.GridTile) {
+	c := change{p, m.Tile(p), t}
+	if c.before == c.after {
+		return
+	}
+	m.set(c, false)
+	if m.painting {
+		m.cur = append(m.cur, c)
+		return
+	}
+	m.undo, m.redo = append(m.undo, stroke{c}), nil
+	m.check()
+}
+
+func (m *Map) set(c change, undo bool) {
+	if undo {
+		m.tiles[c.p.Y()][c.p.X()] = c.before
+	} else {
+		m.tiles[c.p.Y()][c.p.X()] = c.after
+	}
+	m.preview = nil
+}
+
+// Undo takes back the last stroke, it reports false when there is none
+func (m *Map) Undo() bool {
+	m.End()
+	if len(m.undo) == 0 {
+		return false
+	}
+	s := m.undo[len(m.undo)-1]
+	m.undo, m.redo = m.undo[:len(m.undo)-1], append(m.redo, s)
+	for i := len(s) - 1; i >= 0; i-- {
+		m.set(s[i], true)
+	}
+	m.check()
+	return true
+}
+
+// Redo paints the last stroke undone again, it reports false when there is none
+func (m *Map) Redo() bool {
+	m.End()
+	if len(m.redo) == 0 {
+		return false
+	}
+	s := m.redo[len(m.redo)-1]
+	m.redo, m.undo = m.redo[:len(m.redo)-1], append(m.undo, s)
+	for _, c := range s {
+		m.set(c, false)
+	}
+	m.check()
+	return true
+}
+
+// check lays the map out the way the game would and keeps what went wrong
+func (m *Map) check() {
+	m.problem, m.bad = "", nil
+	defer func() {
+		if r := recover(); r != nil {
+			m.problem = fmt.Sprint(r)
+			if match := tileRegEx.FindStringSubmatch(m.problem); match != nil {
+				y, _ := strconv.Atoi(match[1])
+				x, _ := strconv.Atoi(match[2])
+				p := core.Pt(x-1, y-1)
+				m.bad = &p
+			}
+		}
+	}()
+	graph.GridFromData(m.spec, m.String(), m.aa)
+}
+
+// Problem is what keeps the map from being played, empty when it can be
+func (m *Map) Problem() string {
+	return m.problem
+}
+
+// String is the map in the grid map format
+func (m *Map) String() string {
+	return graph.WriteGrid(m.tiles)
+}
+
+// Save writes the map to its file
+func (m *Map) Save() {
+	core.Check(ioutil.WriteFile(m.file, []byte(m.String()), 0644))
+}
+
+// Terrains are the terrains the map can be painted with
+func (m *Map) Terrains() []core.Kind {
+	return m.spec.Terrains()
+}
+
+func (m *Map) File() string {
+	return m.file
+}
+
+// TileSize is the size in pixels of the tiles of the map, the tile size of the map it was opened for
+func (m *Map) TileSize() int {
+	return m.spec.TileSize
+}
+
+func (m *Map) Size() (int, int) {
+	return m.Width() * m.spec.TileSize, m.Height() * m.spec.TileSize
+}
+
+// Draw draws the map with its path joined up, rings around spawns, exits and towers and the tile of the problem
+// outlined
+func (m *Map) Draw(con *gg.Context) {
+	if m.preview == nil {
+		m.preview = graph.PreviewGrid(m.spec, m.tiles, m.aa)
+	}
+	tile := m.spec.TileSize
+	m.preview.Draw(con, tile)
+	for y, row := range m.tiles {
+		for x, t := range row {
+			c, ok := markerColors[t.Meaning]
+			if !ok {
+				continue
+			}
+			center := core.Pt(x, y).Scale(tile).Center(tile)
+			con.SetColor(c)
+			con.SetLineWidth(3)
+			con.DrawCircle(float64(center.X()), float64(center.Y()), float64(tile)/3)
+			con.Stroke()
+			con.DrawStringAnchored(string(t.Meaning[:1]), float64(center.X()), float64(center.Y()), .5, .5)
+		}
+	}
+	if m.bad != nil {
+		con.SetRGB(1, 0, 0)
+		con.SetLineWidth(4)
+		con.DrawRectangle(m.bad.Coordinates(tile))
+		con.Stroke()
+	}
+	con.SetLineWidth(1)
+}
@@ -0,0 +1,177 @@
+package game
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"tdgame/asset"
+	"tdgame/core"
+	"tdgame/editor"
+	"tdgame/graph"
+
+	"github.com/fogleman/gg"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+type (
+	// Editor is the scene designers paint grid maps in with the mouse, the map can be played from it whenever it
+	// has no problems and saved to its file
+	Editor struct {
+		*editor.Map
+		declarations string
+		brushes      []graph.GridTile
+		brush        int
+		play         *Game // the map being played, nil while it is edited
+		status       string
+	}
+)
+
+const (
+	// StatusHeight is the height of the lines under the map with the brushes and the problem of the map
+	StatusHeight = 52
+	// PlayRound is the number of enemies of the round the map is played with
+	PlayRound = 10
+)
+
+var (
+	brushKeys = []ebiten.Key{
+		ebiten.KeyDigit1, ebiten.KeyDigit2, ebiten.KeyDigit3, ebiten.KeyDigit4, ebiten.KeyDigit5,
+		ebiten.KeyDigit6, ebiten.KeyDigit7, ebiten.KeyDigit8, ebiten.KeyDigit9,
+	}
+	editorHelp = "left paint, right erase, 1-9 or wheel brush, ctrl+z undo, ctrl+y redo, ctrl+s save, enter play, g grid"
+)
+
+// NewEditor edits the grid map in file with the terrains and tile size of the declared map, a new map of width by
+// height tiles is made when there is no file
+func NewEditor(declarations, file string, width, height int) *Editor {
+	decs := load(declarations)
+	spec := *decs.Get(graph.GraphType).(graph.GraphAtlas).Graph("map").(graph.CachedImageGraph).Spec()
+	spec.Variety = graph.GridVariety
+	e := &Editor{
+		editor.Open(&spec, file, width, height, decs.Get(asset.AssetType).(asset.AssetAtlas)),
+		declarations,
+		[]graph.GridTile{{Meaning: graph.PathTile}, {Meaning: graph.SpawnTile}, {Meaning: graph.ExitTile}},
+		0,
+		nil,
+		"",
+	}
+	for _, k := range e.Terrains() {
+		e.brushes = append(e.brushes, graph.GridTile{Meaning: k})
+	}
+	return e
+}
+
+func (e *Editor) Update() error {
+	if e.play != nil {
+		if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+			e.play = nil
+			return nil
+		}
+		return e.play.Update()
+	}
+	e.HandleInput()
+	return nil
+}
+
+func (e *Editor) HandleInput() {
+	for i, k := range brushKeys {
+		if i < len(e.brushes) && inpututil.IsKeyJustPressed(k) {
+			e.brush = i
+		}
+	}
+	if _, dy := ebiten.Wheel(); dy > 0 {
+		e.brush = (e.brush + len(e.brushes) - 1) % len(e.brushes)
+	} else if dy < 0 {
+		e.brush = (e.brush + 1) % len(e.brushes)
+	}
+	ctrl, shift := ebiten.IsKeyPressed(ebiten.KeyControl), ebiten.IsKeyPressed(ebiten.KeyShift)
+	switch {
+	case ctrl && inpututil.IsKeyJustPressed(ebiten.KeyY), ctrl && shift && inpututil.IsKeyJustPressed(ebiten.KeyZ):
+		e.Redo()
+	case ctrl && inpututil.IsKeyJustPressed(ebiten.KeyZ):
+		e.Undo()
+	case ctrl && inpututil.IsKeyJustPressed(ebiten.KeyS):
+		e.Save()
+		e.status = "saved to " + e.File()
+	case inpututil.IsKeyJustPressed(ebiten.KeyEnter):
+		e.Play()
+	case inpututil.IsKeyJustPressed(ebiten.KeyG):
+		core.Grid = !core.Grid
+	}
+	x, y := ebiten.CursorPosition()
+	tile := core.Pt(x, y).TileIndex(e.TileSize())
+	left, right := ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft), ebiten.IsMouseButtonPressed(ebiten.MouseButtonRight)
+	if !left && !right {
+		e.End()
+		return
+	}
+	if x < 0 || y < 0 {
+		return
+	}
+	if !e.Painting() {
+		e.Begin()
+	}
+	if left {
+		e.Paint(tile, e.brushes[e.brush])
+	} else {
+		e.Erase(tile)
+	}
+}
+
+// Play plays the map as it is painted unless it has a problem, it is written with a declaration that replaces the
+// declared map and the game is loaded from them with a round of enemies
+func (e *Editor) Play() {
+	if e.Problem() != "" {
+		return
+	}
+	dir, err := ioutil.TempDir("", "tdgame-editor")
+	core.Check(err)
+	defer os.RemoveAll(dir)
+	core.Check(ioutil.WriteFile(path.Join(dir, "map.txt"), []byte(e.String()), 0644))
+	dec := fmt.Sprintf("meta:\n  type: %s\n  variety: %s\n  name: map\nattributes:\n  file: ../map.txt\n  tileSize: %d\n",
+		graph.GraphType, graph.GridVariety, e.TileSize())
+	core.Check(ioutil.WriteFile(path.Join(dir, "map.yaml"), []byte(dec), 0644))
+	e.play = NewGame(e.declarations, path.Join(dir, "map.yaml"))
+	m := e.play.Declarations.Get(graph.GraphType).(graph.GraphAtlas).Graph("map").(graph.CachedImageGraph)
+	e.play.Layers.Add(core.TileLayer, &m)
+	e.play.Layers.Add(core.TileLayer, e.play.NewRound(1, PlayRound))
+	e.status = ""
+}
+
+func (e *Editor) Draw(screen *ebiten.Image) {
+	_, h := e.Size()
+	if e.play != nil {
+		e.play.Draw(screen)
+		ebitenutil.DebugPrintAt(screen, "esc back to the editor", 4, h+2)
+		return
+	}
+	con := gg.NewContext(screen.Size())
+	e.Map.Draw(con)
+	screen.DrawImage(ebiten.NewImageFromImage(con.Image()), &ebiten.DrawImageOptions{})
+	brushes := make([]string, len(e.brushes))
+	for i, b := range e.brushes {
+		brushes[i] = fmt.Sprintf("%d %s", i+1, b.Meaning)
+		if i == e.brush {
+			brushes[i] = "[" + brushes[i] + "]"
+		}
+	}
+	status := e.status
+	if p := e.Problem(); p != "" {
+		status = p
+	} else if status == "" {
+		status = "ready to play"
+	}
+	ebitenutil.DebugPrintAt(screen, strings.Join(brushes, "  "), 4, h+2)
+	ebitenutil.DebugPrintAt(screen, status, 4, h+18)
+	ebitenutil.DebugPrintAt(screen, editorHelp, 4, h+34)
+}
+
+// Layout leaves room under the map for the brushes and the problem of the map
+func (e *Editor) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
+	w, h := e.Size()
+	return w, h + StatusHeight
+}
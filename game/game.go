@@ -24,7 +24,8 @@ type (
 		// r           *td.Round
 		// *ui.UI
 		*core.Declarations
-		t *core.Ticker
+		t     *core.Ticker
+		attrs core.Attributes
 	}
 )
 
@@ -58,7 +59,8 @@ func (g *Game) Update() error {
 	// check for user input
 	g.HandleInput()
 	// process everything
-	g.Layers.Process(g.t.Ticks(), nil)
+	g.Layers.Process(g.t.Ticks(), g.attrs)
+	g.t.Tick()
 	return g.PostUpdate()
 }
 
@@ -171,30 +173,61 @@ func (g *Game) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeigh
 }
 
 func (g *Game) NewRound(round, points int) *td.Round {
-	es := make([]td.Enemy, points)
+	es := make([]core.Kind, points)
 	for i := range es {
 		if i%2 == 0 {
-			es[i] = g.Declarations.Get("enemy").(td.EnemyAtlas).Enemy(core.ZeroLoc, "slug")
-			// es[i] = g.Declarations.Get("enemy").(td.EnemyAtlas).Enemy(g.StartLoc(), "slug")
+			es[i] = "slug"
 		} else {
-			es[i] = g.Declarations.Get("enemy").(td.EnemyAtlas).Enemy(core.ZeroLoc, "spider")
-			// es[i] = g.Declarations.Get("enemy").(td.EnemyAtlas).Enemy(g.StartLoc(), "spider")
+			es[i] = "spider"
 		}
 	}
-	return &td.Round{core.MinGameObject, 0, 96, round, points, core.NewTicker(0), es}
+	return &td.Round{
+		GameObjectNoop: core.MinGameObject,
+		Delay:          96,
+		Round:          round,
+		Points:         points,
+		T:              core.NewTicker(0),
+		Enemies:        es,
+		Atlas:          g.Declarations.Get(td.EnemyType).(*td.EnemyAtlas),
+		Starts:         g.Declarations.Get(graph.GraphType).(graph.GraphAtlas).Graph("map").(graph.CachedImageGraph).StartLocs(),
+	}
+}
+
+// NewPoolMonitor watches the projectile pool of every tower and the pool of every enemy
+func (g *Game) NewPoolMonitor() *core.PoolMonitor {
+	pm := core.NewPoolMonitor()
+	for k, p := range g.Declarations.Get(td.TowerType).(*td.TowerAtlas).Pools() {
+		pm.Add(td.TowerType+"/"+k, p)
+	}
+	for k, p := range g.Declarations.Get(td.EnemyType).(*td.EnemyAtlas).Pools() {
+		pm.Add(td.EnemyType+"/"+k, p)
+	}
+	return pm
 }
 
-func NewGame(declarations string) *Game {
+// load reads every declaration in the directory and then the files, which replace the declarations of the same
+// name in the directory
+func load(declarations string, files ...string) *core.Declarations {
 	decs := core.NewDeclarations()
 	decs.RegisterHandlers(
 		asset.NewAssetAtlas(),
+		graph.NewTerrainAtlas(),
 		graph.NewGraphAtlas(),
 		animator.DefaultAnimatorAtlas,
 		td.NewTowerAtlas(),
 		td.NewEnemyAtlas(),
 	).AddDir(
 		declarations,
-	).Load()
+	)
+	for _, f := range files {
+		decs.AddFile(f)
+	}
+	return decs.Load()
+}
+
+// NewGame loads the declarations in the directory and then the files like load
+func NewGame(declarations string, files ...string) *Game {
+	decs := load(declarations, files...)
 	g := &Game{
 		core.NewLayers(int(core.NumberOfLayers)),
 		// decs.Get(graph.GraphType).(graph.GraphAtlas).Graph("map").(graph.CachedImageGraph),
@@ -210,6 +243,19 @@ func NewGame(declarations string) *Game {
 		// nil,
 		// ui.NewUI(),
 		core.NewTicker(-1), // nearly infinite ticker, ticks until int overflow happens
+		core.NewAttributes(),
+	}
+	g.attrs.SetAttribute(td.StatsKey, td.NewCombatStats())
+	g.Layers.Add(core.EffectLayer, g.NewPoolMonitor())
+	g.Layers.Add(core.EffectLayer, td.NewHealthBarRenderer())
+	g.Layers.Add(core.TileLayer, decs.Get(td.TowerType).(*td.TowerAtlas).Detection())
+	g.Layers.Add(core.TileLayer, decs.Get(td.TowerType).(*td.TowerAtlas).Coverage())
+	m := decs.Get(graph.GraphType).(graph.GraphAtlas).Graph("map").(graph.CachedImageGraph)
+	g.Layers.Add(core.EffectLayer, graph.NewFlowOverlay(core.Debug, m.TileSize, m.Flows()...))
+	for _, pt := range m.Towers() {
+		if t, ok := decs.Get(td.TowerType).(*td.TowerAtlas).Place(core.Loc(pt.Scale(m.TileSize), 0), pt.Kind); ok {
+			g.Layers.Add(core.TowerLayer, t)
+		}
 	}
 	return g
 }
@@ -1,6 +1,6 @@
 module tdgame
 
-go 1.16
+go 1.18
 
 require (
 	github.com/fogleman/gg v1.3.0
@@ -9,3 +9,5 @@ require (
 	golang.org/x/image v0.0.0-20210628002857-a66eb6448b8d
 	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b
 )
+
+require golang.org/x/sys v0.0.0-20210415045647-66c3f260301c // indirect
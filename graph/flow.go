@@ -0,0 +1,221 @@
+package graph
+
+import (
+	"math"
+	"tdgame/core"
+
+	"github.com/fogleman/gg"
+)
+
+type (
+	// FlowField leads every tile it can reach to one exit, the integration field is the cheapest cost of walking
+	// from a tile off the map through the exit and the flow of a tile is the direction of its cheapest neighbor.
+	// Changing the cost of a tile only recomputes the tiles whose cost it can change.
+	FlowField struct {
+		g     BasicGraph
+		Exit  core.Point
+		Out   core.Direction // direction enemies leave the exit in
+		costs []int
+		dists []int
+		flows []core.Direction // 0 for tiles that cannot reach the exit
+		// marks are stamped with mark for the tiles of the last upstream search
+		mark  int
+		marks []int
+		open  openSet
+	}
+	// FlowOverlay draws an arrow along the flow of every tile of its fields when it is visible
+	FlowOverlay struct {
+		fields  []*FlowField
+		tile    int // size in pixels of the tiles of the map of the fields
+		Visible bool
+	}
+)
+
+const (
+	// Impassable is the cost of a tile enemies cannot walk on
+	Impassable = -1
+	// DefaultCost is the cost of a tile of a path or an open tile of a maze, terrain costs are relative to it
+	DefaultCost = 10
+	unreached   = math.MaxInt32
+)
+
+var _ core.GameObject = (*FlowOverlay)(nil)
+
+// PathCost lets enemies walk on every tile of a path, slower through terrain that slows them down
+func PathCost(nd *Node) int {
+	if nd.IsBlank() {
+		return Impassable
+	}
+	return nd.mods.cost()
+}
+
+func NewFlowField(g BasicGraph, exit core.Point, out core.Direction, cost func(*Node) int) *FlowField {
+	n := g.Width() * g.Height()
+	f := &FlowField{g, exit, out, make([]int, n), make([]int, n), make([]core.Direction, n), 0, make([]int, n), make(openSet, 0, n)}
+	for _, row := range g {
+		for _, nd := range row {
+			f.costs[f.idx(nd.Point)] = cost(nd)
+		}
+	}
+	f.Compute()
+	return f
+}
+
+func (f *FlowField) idx(p core.Point) int {
+	return p.Y()*f.g.Width() + p.X()
+}
+
+func (f *FlowField) point(i int) core.Point {
+	return core.Pt(i%f.g.Width(), i/f.g.Width())
+}
+
+// Compute recomputes the whole field from the exit
+func (f *FlowField) Compute() {
+	for i := range f.dists {
+		f.dists[i], f.flows[i] = unreached, 0
+	}
+	f.open = f.open[:0]
+	f.relax(f.Exit)
+	f.run()
+}
+
+// relax gives p the cheapest way out through its neighbors or the exit
+func (f *FlowField) relax(p core.Point) {
+	i := f.idx(p)
+	if f.costs[i] == Impassable {
+		return
+	}
+	if p == f.Exit && f.costs[i] < f.dists[i] {
+		f.dists[i], f.flows[i] = f.costs[i], f.Out
+		f.open.push(openTile{i, f.dists[i]})
+	}
+	for _, n := range f.g.Neighbors(*f.g.Node(p)) {
+		if d := f.dists[f.idx(n.Point)]; d != unreached && d+f.costs[i] < f.dists[i] {
+			f.dists[i], f.flows[i] = d+f.costs[i], n.Direction
+			f.open.push(openTile{i, f.dists[i]})
+		}
+	}
+}
+
+// run is dijkstra from the tiles in the open set
+func (f *FlowField) run() {
+	for len(f.open) > 0 {
+		cur := f.open.pop()
+		if cur.f > f.dists[cur.idx] {
+			continue
+		}
+		for _, n := range f.g.Neighbors(*f.g.Node(f.point(cur.idx))) {
+			ni := f.idx(n.Point)
+			if c := f.costs[ni]; c != Impassable && cur.f+c < f.dists[ni] {
+				f.dists[ni], f.flows[ni] = cur.f+c, n.Direction.Opposite()
+				f.open.push(openTile{ni, f.dists[ni]})
+			}
+		}
+	}
+}
+
+// SetCost changes the cost of walking on a tile, a cheaper tile can only lower the tiles that flow into it
+// while a dearer one unsets every tile that flows through it and routes them again from the tiles around them
+func (f *FlowField) SetCost(p core.Point, cost int) {
+	if !f.g.Contains(p) {
+		return
+	}
+	i := f.idx(p)
+	old := f.costs[i]
+	if old == cost {
+		return
+	}
+	f.costs[i] = cost
+	f.open = f.open[:0]
+	if cost != Impassable && (old == Impassable || cost < old) {
+		f.relax(p)
+		f.run()
+		return
+	}
+	upstream := f.upstream(p)
+	for _, u := range upstream {
+		f.dists[u], f.flows[u] = unreached, 0
+	}
+	for _, u := range upstream {
+		f.relax(f.point(u))
+	}
+	f.run()
+}
+
+// upstream is every tile whose flow passes through p, p included
+func (f *FlowField) upstream(p core.Point) []int {
+	f.mark++
+	ret := []int{f.idx(p)}
+	f.marks[ret[0]] = f.mark
+	for i := 0; i < len(ret); i++ {
+		cur := f.point(ret[i])
+		for _, n := range f.g.Neighbors(*f.g.Node(cur)) {
+			ni := f.idx(n.Point)
+			if f.marks[ni] != f.mark && f.flows[ni] == n.Direction.Opposite() {
+				f.marks[ni] = f.mark
+				ret = append(ret, ni)
+			}
+		}
+	}
+	return ret
+}
+
+func (f *FlowField) Cost(p core.Point) int {
+	if !f.g.Contains(p) {
+		return Impassable
+	}
+	return f.costs[f.idx(p)]
+}
+
+// Distance is the value of the integration field at p, -1 when p cannot reach the exit
+func (f *FlowField) Distance(p core.Point) int {
+	if !f.g.Contains(p) || f.dists[f.idx(p)] == unreached {
+		return -1
+	}
+	return f.dists[f.idx(p)]
+}
+
+// Flow is the direction to walk in from p, false when p cannot reach the exit
+func (f *FlowField) Flow(p core.Point) (core.Direction, bool) {
+	if !f.g.Contains(p) {
+		return 0, false
+	}
+	d := f.flows[f.idx(p)]
+	return d, d != 0
+}
+
+func NewFlowOverlay(visible bool, tile int, fields ...*FlowField) *FlowOverlay {
+	return &FlowOverlay{fields, tile, visible}
+}
+
+func (fo *FlowOverlay) Process(ticks int, con core.Context) bool {
+	return false
+}
+
+func (fo *FlowOverlay) Draw(con *gg.Context) {
+	if !fo.Visible {
+		return
+	}
+	half, head := float64(fo.tile)/2, float64(fo.tile)/8
+	con.SetLineWidth(2)
+	for i, f := range fo.fields {
+		// fields are tinted apart so the arrows of crossing fields can be told apart
+		con.SetRGBA(1, float64(i%3)/2, float64(i%2), .6)
+		for y := 0; y < f.g.Height(); y++ {
+			for x := 0; x < f.g.Width(); x++ {
+				d, ok := f.Flow(core.Pt(x, y))
+				if !ok {
+					continue
+				}
+				cx, cy := core.TileIndexToCoordinate(x, fo.tile)+half, core.TileIndexToCoordinate(y, fo.tile)+half
+				step := core.Pt(0, 0).Neighbor(d)
+				dx, dy := float64(step.X()), float64(step.Y())
+				tx, ty := cx+dx*half*.6, cy+dy*half*.6
+				con.DrawLine(cx-dx*half*.6, cy-dy*half*.6, tx, ty)
+				con.DrawLine(tx, ty, tx-dx*head-dy*head, ty-dy*head+dx*head)
+				con.DrawLine(tx, ty, tx-dx*head+dy*head, ty-dy*head-dx*head)
+			}
+		}
+		con.Stroke()
+	}
+}
@@ -0,0 +1,254 @@
+package graph
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"tdgame/asset"
+	"tdgame/core"
+)
+
+type (
+	// generator lays out a map from the attributes of a generated graph, every choice comes from one source
+	// seeded with the seed string so the same seed and attributes always make the same map
+	generator struct {
+		*GraphAttributes
+		rng   *rand.Rand
+		used  map[core.Point]bool
+		tiles []core.Point // tiles of the path in the order enemies walk them
+	}
+)
+
+const (
+	GeneratedVariety = "generated"
+	// generateAttempts is how many random paths are tried before the attributes are deemed impossible
+	generateAttempts = 5000
+	// obstacleChance is the chance in 100 of a tile off the path being rock or water
+	obstacleChance = 10
+)
+
+// GeneratedFromSpec makes a map with a path that enters through a border and leaves through another without
+// touching itself, branches that go the other way around some of its turns and rock and water scattered
+// around them
+func GeneratedFromSpec(spec *GraphSpec, aa asset.AssetAtlas) CachedImageGraph {
+	gen := newGenerator(&spec.GraphAttributes)
+	start, dirs := gen.path()
+	branches := gen.branches(dirs)
+	g := NewGraph(spec.Width, spec.Height)
+	gen.scatter(g)
+	ps, segments := g.layPaths([]core.Point{start}, [][]core.Direction{dirs}, branches, aa)
+	return cache(spec, g, ps, segments, PathCost, aa)
+}
+
+func newGenerator(ga *GraphAttributes) *generator {
+	if ga.Width < 3 || ga.Height < 3 {
+		panic(fmt.Sprintf("generated map must be at least 3x3 tiles, not %dx%d", ga.Width, ga.Height))
+	}
+	if ga.Length.Max == 0 {
+		ga.Length.Max = ga.Width * ga.Height
+	}
+	if ga.Length.Min > ga.Length.Max || ga.Turns < 0 || ga.Branchiness < 0 || ga.Branchiness > 1 {
+		panic(fmt.Sprintf("generated map with seed %q: length min must not be over max, turns must not be negative and branchiness must be between 0 and 1", ga.Seed))
+	}
+	h := fnv.New64a()
+	h.Write([]byte(ga.Seed))
+	return &generator{ga, rand.New(rand.NewSource(int64(h.Sum64()))), nil, nil}
+}
+
+// path tries random paths until one has the length and turns of the attributes
+func (gen *generator) path() (core.Point, []core.Direction) {
+	for i := 0; i < generateAttempts; i++ {
+		if dirs, ok := gen.try(); ok {
+			return gen.tiles[0], dirs
+		}
+	}
+	panic(fmt.Sprintf("generated map with seed %q: no path of %d to %d tiles with %d turns fits on %dx%d tiles",
+		gen.Seed, gen.Length.Min, gen.Length.Max, gen.Turns, gen.Width, gen.Height))
+}
+
+// try walks runs of at least 2 tiles from a random tile of a border, turning left or right between them,
+// the last run goes straight until it leaves through another border
+func (gen *generator) try() ([]core.Direction, bool) {
+	w, h := gen.Width, gen.Height
+	heading := core.Directions[gen.rng.Intn(len(core.Directions))]
+	var p core.Point
+	switch heading {
+	case core.S:
+		p = core.Pt(1+gen.rng.Intn(w-2), 0)
+	case core.N:
+		p = core.Pt(1+gen.rng.Intn(w-2), h-1)
+	case core.E:
+		p = core.Pt(0, 1+gen.rng.Intn(h-2))
+	default:
+		p = core.Pt(w-1, 1+gen.rng.Intn(h-2))
+	}
+	first := heading
+	gen.used, gen.tiles = map[core.Point]bool{p: true}, []core.Point{p}
+	dirs := make([]core.Direction, 0)
+	for turn := 0; turn <= gen.Turns; turn++ {
+		n, last := gen.ahead(p, heading), turn == gen.Turns
+		if last {
+			// the run must reach the border, which is the tile after the last free one
+			if !gen.free(p, heading, n+1) || !gen.onBorder(gen.step(p, heading, n+1)) {
+				return nil, false
+			}
+			n++
+		} else if n < 2 {
+			return nil, false
+		} else {
+			n = 2 + gen.rng.Intn(n-1)
+		}
+		for i := 0; i < n; i++ {
+			p = p.Neighbor(heading)
+			gen.used[p], gen.tiles, dirs = true, append(gen.tiles, p), append(dirs, heading)
+		}
+		if !last {
+			if heading = gen.turn(p, heading, first, turn+1 == gen.Turns); heading == 0 {
+				return nil, false
+			}
+		}
+	}
+	l := len(gen.tiles)
+	return dirs, l >= gen.Length.Min && l <= gen.Length.Max
+}
+
+// turn picks a side to turn to at p that leaves room for the next run, the last run must be able to go
+// straight to a border other than the one the path started from. It is 0 when neither side can.
+func (gen *generator) turn(p core.Point, heading, first core.Direction, last bool) core.Direction {
+	sides := make([]core.Direction, 0, 2)
+	for _, d := range []core.Direction{turn90(heading, true), turn90(heading, false)} {
+		n := gen.ahead(p, d)
+		if last && d != first.Opposite() && gen.free(p, d, n+1) && gen.onBorder(gen.step(p, d, n+1)) || !last && n >= 2 {
+			sides = append(sides, d)
+		}
+	}
+	if len(sides) == 0 {
+		return 0
+	}
+	return sides[gen.rng.Intn(len(sides))]
+}
+
+// ahead is how many tiles in a row from p in d are free and off the border
+func (gen *generator) ahead(p core.Point, d core.Direction) int {
+	n := 0
+	for gen.free(p, d, n+1) && !gen.onBorder(gen.step(p, d, n+1)) {
+		n++
+	}
+	return n
+}
+
+// free reports whether the tile n tiles from p in d is clear of every used tile but the one before it
+func (gen *generator) free(p core.Point, d core.Direction, n int) bool {
+	return gen.clear(gen.step(p, d, n), gen.step(p, d, n-1))
+}
+
+// clear reports whether t is on the map, unused and touches no used tile besides those allowed
+func (gen *generator) clear(t core.Point, allowed ...core.Point) bool {
+	if t.X() < 0 || t.Y() < 0 || t.X() >= gen.Width || t.Y() >= gen.Height || gen.used[t] {
+		return false
+	}
+	for _, d := range core.Directions {
+		n := t.Neighbor(d)
+		if gen.used[n] && !containsPoint(allowed, n) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsPoint(ps []core.Point, p core.Point) bool {
+	for _, o := range ps {
+		if o == p {
+			return true
+		}
+	}
+	return false
+}
+
+func (gen *generator) step(p core.Point, d core.Direction, n int) core.Point {
+	for i := 0; i < n; i++ {
+		p = p.Neighbor(d)
+	}
+	return p
+}
+
+func (gen *generator) onBorder(p core.Point) bool {
+	return p.X() == 0 || p.Y() == 0 || p.X() == gen.Width-1 || p.Y() == gen.Height-1
+}
+
+// turn90 is the direction a quarter turn right or left of d
+func turn90(d core.Direction, right bool) core.Direction {
+	if right {
+		return d%4 + 1
+	}
+	return (d+2)%4 + 1
+}
+
+// branches go the other way around turns of the path, a branch leaves the run into a turn, walks alongside the
+// run out of it and merges back into it
+func (gen *generator) branches(dirs []core.Direction) []Branch {
+	ret := make([]Branch, 0)
+	for i := 1; i < len(dirs); i++ {
+		a, b := dirs[i-1], dirs[i]
+		if a == b || gen.rng.Float64() >= gen.Branchiness {
+			continue
+		}
+		in, out := 0, 0
+		for in < i && dirs[i-1-in] == a {
+			in++
+		}
+		for i+out < len(dirs) && dirs[i+out] == b {
+			out++
+		}
+		// keep the ends of the branch off the first and last tile of the path
+		in, out = core.MinInt(in, i-1), core.MinInt(out, len(dirs)-i-1)
+		if in < 2 || out < 2 {
+			continue
+		}
+		k, d := 2+gen.rng.Intn(in-1), 2+gen.rng.Intn(out-1)
+		if br := gen.branch(gen.tiles[i-k], b, d, a, k); br != nil {
+			ret = append(ret, *br)
+		}
+	}
+	return ret
+}
+
+// branch walks n tiles in d and then m tiles in e from a tile of the path, it is nil when the walk does not end
+// on the path or crosses a tile that is not free
+func (gen *generator) branch(from core.Point, d core.Direction, n int, e core.Direction, m int) *Branch {
+	dirs, p, end := make([]core.Direction, 0, n+m), from, gen.step(gen.step(from, d, n), e, m)
+	if !gen.used[end] {
+		return nil
+	}
+	for i := 0; i < n+m; i++ {
+		dir := d
+		if i >= n {
+			dir = e
+		}
+		next := p.Neighbor(dir)
+		if next != end && (!gen.clear(next, p, end) || gen.onBorder(next)) {
+			return nil
+		}
+		p, dirs = next, append(dirs, dir)
+	}
+	for i, q := 0, from; i < len(dirs)-1; i++ {
+		q = q.Neighbor(dirs[i])
+		gen.used[q] = true
+	}
+	return &Branch{from, 1, dirs}
+}
+
+// scatter turns some of the tiles off the paths into rock or water
+func (gen *generator) scatter(g BasicGraph) {
+	for _, row := range g {
+		for _, nd := range row {
+			if gen.used[nd.Point] || gen.rng.Intn(100) >= obstacleChance {
+				continue
+			}
+			nd.terrain = Rock
+			if gen.rng.Intn(2) == 0 {
+				nd.terrain = Water
+			}
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package graph
+
+import (
+	"reflect"
+	"tdgame/core"
+	"testing"
+)
+
+func generated(t *testing.T, seed string) (core.Point, []core.Direction, []Branch, []core.Point, BasicGraph) {
+	t.Helper()
+	gen := newGenerator(&GraphAttributes{Width: 12, Height: 10, Seed: seed, Length: core.Range{Min: 12}, Turns: 4, Branchiness: .5})
+	start, dirs := gen.path()
+	branches := gen.branches(dirs)
+	g := NewGraph(12, 10)
+	gen.scatter(g)
+	return start, dirs, branches, gen.tiles, g
+}
+
+func TestGeneratedSameSeed(t *testing.T) {
+	for _, seed := range []string{"a", "tdgame", "42"} {
+		start, dirs, branches, _, g := generated(t, seed)
+		start2, dirs2, branches2, _, g2 := generated(t, seed)
+		if start != start2 || !reflect.DeepEqual(dirs, dirs2) || !reflect.DeepEqual(branches, branches2) {
+			t.Errorf("seed %q: paths differ, %s %v and %s %v", seed, start, dirs, start2, dirs2)
+		}
+		for y, row := range g {
+			for x, nd := range row {
+				if nd.terrain != g2[y][x].terrain {
+					t.Errorf("seed %q: tile (%d,%d) is %q and %q", seed, x, y, nd.terrain, g2[y][x].terrain)
+				}
+			}
+		}
+	}
+}
+
+func TestGeneratedPath(t *testing.T) {
+	for _, seed := range []string{"a", "tdgame", "42"} {
+		_, _, _, tiles, _ := generated(t, seed)
+		seen := make(map[core.Point]bool)
+		for _, p := range tiles {
+			if seen[p] {
+				t.Errorf("seed %q: path crosses itself at %s", seed, p)
+			}
+			seen[p] = true
+		}
+		first, last := tiles[0], tiles[len(tiles)-1]
+		onBorder := func(p core.Point) bool {
+			return p.X() == 0 || p.Y() == 0 || p.X() == 11 || p.Y() == 9
+		}
+		if !onBorder(first) || !onBorder(last) {
+			t.Errorf("seed %q: path goes from %s to %s, not border to border", seed, first, last)
+		}
+	}
+}
@@ -23,14 +23,28 @@ type (
 		Height() int
 		Node(core.Point) *Node
 		TLoc(offset, size core.Point) *TileLocation
+		DamageablesWithin(p core.Point, radius int) []Damageable
+		ModifiersAt(p core.Point) Modifiers
 	}
 	GraphAttributes struct {
 		File string
+		// a maze has no file, it is an open field of Width by Height tiles with spawns and exits as "x,y"
+		Width, Height int
+		Spawns, Exits []string
+		// a generated map has no file either, Seed makes its path of Length tiles that turns Turns times and
+		// Branchiness is the share of the turns that get a branch around them
+		Seed        string
+		Length      core.Range
+		Turns       int
+		Branchiness float64
+		// TileSize is the size in pixels of the tiles of the map, the assets it uses are drawn for tiles that size
+		TileSize int `yaml:"tileSize"`
 	}
 	GraphSpec struct {
 		core.Meta
 		GraphAttributes
 		FilePath string
+		terrains TerrainAtlas // the declared terrains, the defaults are used for the others
 	}
 	GraphAtlas map[core.Kind]Graph
 )
@@ -44,6 +58,8 @@ type (
 	}
 	Collider interface {
 		Location() core.Location
+		// Center is the middle of the tile sized box at the location of the collider
+		Center() core.Point
 		Radius() int
 		Near(Collider) bool
 		Kind() core.Kind
@@ -54,32 +70,55 @@ type (
 	}
 	Damager interface {
 		Collider
-		DoDamage(Damageable)
+		DoDamage(Damageable, core.Context)
 	}
 	Node struct {
 		dables        []Damageable
 		dmgers        []Damager
-		distanceToEnd int
+		distanceToEnd int   // distance to the closest exit of any path
+		distances     []int // distance to the exit of each path, -1 when the node is not on the path
 		core.Point
-		k core.Kind
-		a asset.Asset
+		k       core.Kind
+		a       asset.Asset
+		terrain core.Kind // the ground under the node, grass unless the map says otherwise
+		mods    Modifiers
+		tint    color.Color // drawn over blank nodes whose terrain has no asset
+		// diagonal is set on the corner tile of a diagonal step, enemies cut across it instead of turning
+		diagonal bool
 	}
 	NodeDirection struct {
 		core.Direction
 		*Node
 	}
-	Nodes            []*Node
-	BasicGraph       []Nodes
+	Nodes      []*Node
+	BasicGraph []Nodes
+	// Path is a route through the map from a spawn to an exit, Entry is the direction enemies are moving in
+	// when they reach Start and Exit is the direction they leave End in
+	Path struct {
+		Start, End  core.Point
+		Entry, Exit core.Direction
+		First       *Segment // the segment enemies spawned on the path start on
+		kinds       []core.Kind
+		tile        int // size in pixels of the tiles of the map, set once the map is cached
+	}
 	CachedImageGraph struct {
 		*GraphSpec
 		// imageWithGrid, image *ebiten.Image
 		imageWithGrid, image image.Image
-		start                core.Point
-		path                 []core.Kind
+		paths                []*Path
+		segments             []*Segment
+		maze                 *Maze
+		flows                []*FlowField // the flow field to the exit of each path, paths to one exit share it
+		towers               []PlacedTower
 		BasicGraph
 	}
 )
 
+var (
+	// NoTiles is the tile set of a TileLocation that has not been placed on the graph yet
+	NoTiles = [4]core.Point{core.Pt(-1, -1), core.Pt(-1, -1), core.Pt(-1, -1), core.Pt(-1, -1)}
+)
+
 const (
 	Priority      = 1
 	GraphType     = "graph"
@@ -99,7 +138,7 @@ func (ga GraphAtlas) Type() core.Kind {
 
 func (ga GraphAtlas) Match(pm *core.PreMeta) (spec core.Kinder, priority int) {
 	switch pm.Variety {
-	case CachedVariety:
+	case CachedVariety, MazeVariety, GridVariety, TiledVariety, GeneratedVariety:
 		return &GraphSpec{FilePath: pm.FilePath}, 2
 	default:
 		panic("variety of graph does not exist")
@@ -113,13 +152,37 @@ func (ga GraphAtlas) PreLoad(d *core.Declarations) {
 func (ga GraphAtlas) Load(spec core.Kinder, decs *core.Declarations) {
 	switch spec.(type) {
 	case *GraphSpec:
-		g := spec.(*GraphSpec)
-		ga[g.Name] = GraphFromSpec(g, decs.Get(asset.AssetType).(asset.AssetAtlas))
+		g, aa := spec.(*GraphSpec), decs.Get(asset.AssetType).(asset.AssetAtlas)
+		g.terrains, _ = decs.Get(TerrainType).(TerrainAtlas)
+		g.checkTileSize()
+		switch g.Variety {
+		case MazeVariety:
+			ga[g.Name] = MazeFromSpec(g, aa)
+		case GridVariety:
+			ga[g.Name] = GridFromSpec(g, aa)
+		case TiledVariety:
+			ga[g.Name] = TiledFromSpec(g, aa)
+		case GeneratedVariety:
+			ga[g.Name] = GeneratedFromSpec(g, aa)
+		default:
+			ga[g.Name] = GraphFromSpec(g, aa)
+		}
 	default:
 		panic("variety of graph does not exist")
 	}
 }
 
+// checkTileSize gives a spec that does not declare a tile size the default one, everything placed on the map
+// takes the size of its tiles from the spec
+func (spec *GraphSpec) checkTileSize() {
+	if spec.TileSize == 0 {
+		spec.TileSize = core.DefaultTileSize
+	}
+	if spec.TileSize < 8 {
+		panic(fmt.Sprintf("map %s: tile size must be at least 8 pixels, not %d", spec.Name, spec.TileSize))
+	}
+}
+
 func (ga GraphAtlas) Graph(k core.Kind) Graph {
 	return ga[k]
 }
@@ -127,29 +190,31 @@ func (ga GraphAtlas) Graph(k core.Kind) Graph {
 func (g CachedImageGraph) TLoc(offset, size core.Point) *TileLocation {
 	return &TileLocation{
 		g,
-		nil,
+		core.LocWrapper(core.ZeroLoc),
 		offset,
 		size,
-		[4]core.Point{},
+		NoTiles,
 	}
 }
 
 func (t *TileLocation) calculateTiles(col Collider) {
-	center, half := t.LocationWrapper.Location().Add(t.Offset), t.Size.Reduce(2)
+	half := t.Size.Reduce(2)
+	center := t.LocationWrapper.Location().Add(t.Offset).Add(half)
 	ohalf := half.Multiply(core.Pt(1, -1))
 	nw := center.Subtract(half)
 	ne := center.Add(ohalf)
 	sw := center.Subtract(ohalf)
 	se := center.Add(half)
-	tiles := [4]core.Point{nw.TileIndex(), ne.TileIndex(), sw.TileIndex(), se.TileIndex()}
+	tile := t.TileSize()
+	tiles := [4]core.Point{nw.TileIndex(tile), ne.TileIndex(tile), sw.TileIndex(tile), se.TileIndex(tile)}
 	for i := 0; i < 4; i++ {
 		tile, match := t.Tiles[i], false
 		for j := 0; j < 4; j++ {
 			match = match || tile == tiles[j]
 		}
-		if !match {
+		if nd := t.g.Node(tile); !match && nd != nil {
 			// do remove, tile is not in new tiles
-			t.g.Node(tile).Remove(col)
+			nd.Remove(col)
 		}
 	}
 	for i := 0; i < 4; i++ {
@@ -157,9 +222,9 @@ func (t *TileLocation) calculateTiles(col Collider) {
 		for j := 0; j < 4; j++ {
 			match = match || tile == t.Tiles[j]
 		}
-		if !match {
+		if nd := t.g.Node(tile); !match && nd != nil {
 			// do add, tile is not in old tiles
-			t.g.Node(tile).Add(col)
+			nd.Add(col)
 		}
 	}
 	t.Tiles = tiles
@@ -174,24 +239,67 @@ func (t *TileLocation) Move(l core.Location, col Collider) {
 	t.calculateTiles(col)
 }
 
+func (t *TileLocation) Graph() Graph {
+	return t.g
+}
+
+// TileSize is the size in pixels of the tiles of the graph the location is on
+func (t *TileLocation) TileSize() int {
+	return t.g.Spec().TileSize
+}
+
+func (t *TileLocation) Center() core.Point {
+	return t.Location().Center(t.TileSize())
+}
+
+// Clear removes the collider from every tile it currently occupies.
+func (t *TileLocation) Clear(col Collider) {
+	for _, tile := range t.Tiles {
+		if nd := t.g.Node(tile); nd != nil {
+			nd.Remove(col)
+		}
+	}
+	t.Tiles = NoTiles
+}
+
 func (t *TileLocation) Copy() *TileLocation {
 	return t.g.TLoc(t.Offset, t.Size)
 }
 
 func BlankNode(p core.Point) *Node {
-	return &Node{make([]Damageable, 0), make([]Damager, 0), 0, p, core.Bl, &asset.StaticAsset{}}
+	return &Node{make([]Damageable, 0), make([]Damager, 0), 0, nil, p, core.Bl, &asset.StaticAsset{}, Grass, NoModifiers, nil, false}
 }
 
 func Nd(dist int, p core.Point, k core.Kind, a asset.Asset) *Node {
-	return &Node{make([]Damageable, 0), make([]Damager, 0), dist, p, k, a}
+	return &Node{make([]Damageable, 0), make([]Damager, 0), dist, nil, p, k, a, Grass, NoModifiers, nil, false}
 }
 
 func (n Node) IsBlank() bool {
 	return n.k == core.Bl
 }
 
-func (n Node) Draw(con *gg.Context) {
-	n.a.Draw(con, core.Loc(n.Point.Scale(64), 0))
+// Draw draws the node on a map with tiles tile pixels wide
+func (n Node) Draw(con *gg.Context, tile int) {
+	n.a.Draw(con, core.Loc(n.Point.Scale(tile), 0))
+	if n.tint != nil && n.IsBlank() {
+		con.SetColor(n.tint)
+		con.DrawRectangle(n.Point.Coordinates(tile))
+		con.Fill()
+	}
+}
+
+func (n *Node) Terrain() core.Kind {
+	return n.terrain
+}
+
+// Diagonal reports whether enemies walk straight across the node from the tile before it to the tile after it
+func (n *Node) Diagonal() bool {
+	return n.diagonal
+}
+
+// Buildable reports whether a tower can be built on the node, only off the path on a terrain that allows it
+func (n *Node) Buildable() bool {
+	return n.IsBlank() && n.mods.Buildable
 }
 
 func (n Node) String() string {
@@ -202,7 +310,19 @@ func (n Node) String() string {
 	}
 }
 
-func (n Node) Add(col Collider) {
+// DistanceToEnd is the number of tiles from the node to the exit of path i, -1 if the node is not on it
+func (n *Node) DistanceToEnd(i int) int {
+	if i >= len(n.distances) {
+		return -1
+	}
+	return n.distances[i]
+}
+
+func (n *Node) Damageables() []Damageable {
+	return n.dables
+}
+
+func (n *Node) Add(col Collider) {
 	switch d := col.(type) {
 	case Damageable:
 		n.dables = append(n.dables, d)
@@ -213,18 +333,20 @@ func (n Node) Add(col Collider) {
 	}
 }
 
-func (n Node) Remove(col Collider) {
+func (n *Node) Remove(col Collider) {
 	switch d := col.(type) {
 	case Damageable:
 		for i, dable := range n.dables {
 			if dable == d {
 				n.dables = append(n.dables[:i], n.dables[i+1:]...)
+				break
 			}
 		}
 	case Damager:
 		for i, dmger := range n.dmgers {
 			if dmger == d {
 				n.dmgers = append(n.dmgers[:i], n.dmgers[i+1:]...)
+				break
 			}
 		}
 	default:
@@ -232,11 +354,11 @@ func (n Node) Remove(col Collider) {
 	}
 }
 
-func (n Node) Process(ticks int, con core.Context) bool {
+func (n *Node) Process(ticks int, con core.Context) bool {
 	for _, dmger := range n.dmgers {
 		for _, dable := range n.dables {
 			if dmger.Near(dable) {
-				dmger.DoDamage(dable)
+				dmger.DoDamage(dable, con)
 				// if dable.IsDead() {
 				// 	con.Remove(core.EnemyLayer, dable)
 				// }
@@ -266,83 +388,283 @@ func NewGraph(width, height int) BasicGraph {
 func GraphFromSpec(spec *GraphSpec, aa asset.AssetAtlas) CachedImageGraph {
 	data, err := ioutil.ReadFile(path.Join(spec.FilePath, spec.File))
 	core.Check(err)
-	sdata := string(data)
-	lines := strings.Split(sdata, "\n")
-	if len(lines) < 1 {
-		panic("must have at least 1 line of core.Directions")
+	starts, paths, branches := make([]core.Point, 0), make([][]core.Direction, 0), make([]Branch, 0)
+	// every line with a point starts a new path, or a branch when it starts with fork, the lines after it are
+	// the directions of that path or branch. A line of two directions is a diagonal step, it is laid as a step
+	// in each and enemies cut across the corner tile between them.
+	forking, at, corners := false, core.ZeroPt, make([]core.Point, 0)
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if p, ok := parsePoint(line); ok {
+			if forking = strings.HasPrefix(line, ForkPrefix); forking {
+				branches = append(branches, Branch{p, forkWeight(line), make([]core.Direction, 0)})
+			} else {
+				starts, paths = append(starts, p), append(paths, make([]core.Direction, 0))
+			}
+			at = p
+			continue
+		}
+		if len(paths) == 0 {
+			panic("first line should include start point")
+		}
+		dirs := steps(line)
+		if len(dirs) == 2 {
+			corners = append(corners, at.Neighbor(dirs[0]))
+		}
+		for _, d := range dirs {
+			at = at.Neighbor(d)
+		}
+		if forking {
+			b := &branches[len(branches)-1]
+			b.Dirs = append(b.Dirs, dirs...)
+		} else {
+			paths[len(paths)-1] = append(paths[len(paths)-1], dirs...)
+		}
+	}
+	if len(paths) == 0 {
+		panic("must have at least 1 path")
+	}
+	g := GraphFromPaths(spec, starts, paths, branches, aa)
+	for _, p := range corners {
+		g.Node(p).diagonal = true
 	}
-	pStrs := core.PointRegEx.FindStringSubmatch(lines[0])
+	return g
+}
+
+// steps are the directions of a line of a path, one for a straight step and two for a diagonal one
+func steps(line string) []core.Direction {
+	switch len(line) {
+	case 1:
+		return []core.Direction{core.StringToDirection(line)}
+	case 2:
+		first, second := core.StringToDirection(line[:1]), core.StringToDirection(line[1:])
+		if first == second || first == second.Opposite() {
+			panic(fmt.Sprintf("diagonal step %s must be made of two directions at right angles", line))
+		}
+		return []core.Direction{first, second}
+	default:
+		panic(fmt.Sprintf("path step %s must be a direction or two for a diagonal", line))
+	}
+}
+
+// parsePoint is the first "x,y" in s
+func parsePoint(s string) (core.Point, bool) {
+	pStrs := core.PointRegEx.FindStringSubmatch(s)
 	if pStrs == nil {
-		panic("first line should include start point")
+		return core.ZeroPt, false
 	}
-	lines = lines[1:]
 	x, err := strconv.Atoi(pStrs[1])
 	core.Check(err)
 	y, err := strconv.Atoi(pStrs[2])
 	core.Check(err)
-	p, dirs := core.Pt(x, y), make([]core.Direction, len(lines))
-	for i, line := range lines {
-		dirs[i] = core.StringToDirection(strings.TrimSpace(line))
+	return core.Pt(x, y), true
+}
+
+// forkWeight is the optional weight after the point of a fork line, 1 when there is none
+func forkWeight(line string) int {
+	fields := strings.Fields(line)
+	if len(fields) < 3 {
+		return 1
 	}
-	return GraphFromPath(spec, p, dirs, aa)
+	w, err := strconv.Atoi(fields[2])
+	core.Check(err)
+	return w
 }
 
 func GraphFromPath(spec *GraphSpec, start core.Point, dirs []core.Direction, aa asset.AssetAtlas) CachedImageGraph {
-	p, width, height := start, start.X(), start.Y()
-	if width < 0 || height < 0 {
-		panic("start point coordinates must be positive")
-	}
-	if width != 0 && height != 0 {
-		panic("start point must be on West or North border of map")
-	}
-	for _, d := range dirs {
-		p = p.Neighbor(d)
+	return GraphFromPaths(spec, []core.Point{start}, [][]core.Direction{dirs}, nil, aa)
+}
+
+// GraphFromPaths creates a graph big enough for every path and branch. A path that starts or ends on a border
+// of the map enters or leaves through that border, one that starts or ends inside of the map is a portal that
+// enemies enter or leave moving in the direction of the first or last step of the path.
+func GraphFromPaths(spec *GraphSpec, starts []core.Point, paths [][]core.Direction, branches []Branch, aa asset.AssetAtlas) CachedImageGraph {
+	width, height := 0, 0
+	walk := func(p core.Point, dirs []core.Direction) {
 		if p.X() < 0 || p.Y() < 0 {
-			panic("point in path has negative coordinates which are not allowed")
+			panic("start point coordinates must be positive")
 		}
 		width, height = core.MaxInt(width, p.X()), core.MaxInt(height, p.Y())
+		for _, d := range dirs {
+			p = p.Neighbor(d)
+			if p.X() < 0 || p.Y() < 0 {
+				panic("point in path has negative coordinates which are not allowed")
+			}
+			width, height = core.MaxInt(width, p.X()), core.MaxInt(height, p.Y())
+		}
 	}
-	end, g := p, NewGraph(width+1, height+1)
-	if end.X() != width && end.Y() != height {
-		panic("end point must be on East or South border of map")
-	}
-	if start.Y() == 0 {
-		dirs = append([]core.Direction{core.S}, dirs...)
-	} else { // start.x == 0
-		dirs = append([]core.Direction{core.E}, dirs...)
-	}
-	if end.Y() == height {
-		dirs = append(dirs, core.S)
-	} else { // end.x == g.Width()
-		dirs = append(dirs, core.E)
-	}
-	p = start
-	kinds := make([]core.Kind, len(dirs)-1)
-	for i := 1; i < len(dirs); i++ {
-		entry, exit := dirs[i-1], dirs[i]
-		k := core.DirectionsToKind(entry, exit)
-		kinds[i-1] = k
-		g[p.Y()][p.X()] = Nd(len(dirs)-i, p, k, aa.Asset(k))
-		p = p.Neighbor(exit)
-	}
-	kinds = append(kinds, core.DirectionsToKind(dirs[len(dirs)-1], dirs[len(dirs)-1]))
-	blankAsset := aa.Blank()
-	for _, row := range g {
-		for _, n := range row {
-			if n.IsBlank() {
-				n.a = blankAsset
+	for i, dirs := range paths {
+		walk(starts[i], dirs)
+	}
+	for _, b := range branches {
+		walk(b.From, b.Dirs)
+	}
+	g := NewGraph(width+1, height+1)
+	ps, segments := g.layPaths(starts, paths, branches, aa)
+	return cache(spec, g, ps, segments, PathCost, aa)
+}
+
+// layPaths lays paths and branches onto the graph, routes them and splits them into segments
+func (g BasicGraph) layPaths(starts []core.Point, paths [][]core.Direction, branches []Branch, aa asset.AssetAtlas) ([]*Path, []*Segment) {
+	ends := make([]core.Point, len(paths))
+	for i, dirs := range paths {
+		p := starts[i]
+		for _, d := range dirs {
+			p = p.Neighbor(d)
+		}
+		if !g.Contains(starts[i]) || !g.Contains(p) {
+			panic("path must start and end on the map")
+		}
+		ends[i] = p
+	}
+	ps, r := make([]*Path, len(paths)), make(router)
+	for i, dirs := range paths {
+		ps[i] = g.addPath(i, len(paths), starts[i], ends[i], dirs, aa)
+		r.addPath(ps[i], dirs)
+	}
+	merges := make([]merge, 0, len(branches))
+	for _, b := range branches {
+		if m, ok := g.addBranch(r, b, aa); ok {
+			merges = append(merges, m)
+		}
+	}
+	for _, m := range merges {
+		r.merge(m)
+	}
+	g.addJunctions(r, aa)
+	g.setDistances(r)
+	return ps, r.segments(ps, g.Width()*g.Height()*4)
+}
+
+// cache applies the terrain of every node, sizes everything on the graph to its tiles, draws the graph once with
+// and once without the grid and computes the flow field of every exit with the cost of walking on each node
+func cache(spec *GraphSpec, g BasicGraph, ps []*Path, segments []*Segment, cost func(*Node) int, aa asset.AssetAtlas) CachedImageGraph {
+	spec.applyTerrain(g, aa)
+	g.sizeAssets(spec.TileSize)
+	for _, p := range ps {
+		p.tile = spec.TileSize
+	}
+	for _, s := range segments {
+		s.tile = spec.TileSize
+	}
+	flows := make([]*FlowField, len(ps))
+	for i, p := range ps {
+		for j := 0; j < i && flows[i] == nil; j++ {
+			if ps[j].End == p.End && ps[j].Exit == p.Exit {
+				flows[i] = flows[j]
 			}
 		}
+		if flows[i] == nil {
+			flows[i] = NewFlowField(g, p.End, p.Exit, cost)
+		}
+	}
+	eimgWithGrid, eimg := render(g, spec.TileSize)
+	return CachedImageGraph{spec, eimgWithGrid, eimg, ps, segments, nil, flows, nil, g}
+}
+
+// render draws the graph with tiles tile pixels wide once with and once without the grid, over is drawn on top
+// of the nodes
+func render(g BasicGraph, tile int, over ...core.Drawer) (image.Image, image.Image) {
+	imgs := make([]image.Image, 2)
+	for i, grid := range []bool{false, true} {
+		core.Grid = grid
+		con := gg.NewContext(g.Width()*tile, g.Height()*tile)
+		g.drawNodes(con, tile)
+		for _, d := range over {
+			d.Draw(con)
+		}
+		if grid {
+			g.drawGrid(con, tile)
+		}
+		imgs[i] = con.Image() // ebiten.NewImageFromImage(con.Image())
+	}
+	return imgs[1], imgs[0]
+}
+
+// borderDirection is the first of candidates, the directions through the borders a point is on, or d when the
+// point is not on a border
+func borderDirection(d core.Direction, candidates ...core.Direction) core.Direction {
+	if len(candidates) > 0 {
+		return candidates[0]
 	}
-	core.Grid = false
-	con := gg.NewContext(g.Size())
-	g.Draw(con)
-	eimg := con.Image() // ebiten.NewImageFromImage(con.Image())
-	core.Grid = true
-	con = gg.NewContext(g.Size())
-	g.Draw(con)
-	eimgWithGrid := con.Image() // ebiten.NewImageFromImage(con.Image())
-	return CachedImageGraph{spec, eimgWithGrid, eimg, start, kinds, g}
+	return d
+}
+
+// entries are the directions enemies can enter p moving in through the borders it is on
+func (g BasicGraph) entries(p core.Point) []core.Direction {
+	ret, width, height := make([]core.Direction, 0, 2), g.Width()-1, g.Height()-1
+	if p.Y() == 0 {
+		ret = append(ret, core.S)
+	}
+	if p.X() == 0 {
+		ret = append(ret, core.E)
+	}
+	if p.X() == width {
+		ret = append(ret, core.W)
+	}
+	if p.Y() == height {
+		ret = append(ret, core.N)
+	}
+	return ret
+}
+
+// exits are the directions enemies can leave the map from p in through the borders it is on
+func (g BasicGraph) exits(p core.Point) []core.Direction {
+	ret, width, height := make([]core.Direction, 0, 2), g.Width()-1, g.Height()-1
+	if p.Y() == height {
+		ret = append(ret, core.S)
+	}
+	if p.X() == width {
+		ret = append(ret, core.E)
+	}
+	if p.Y() == 0 {
+		ret = append(ret, core.N)
+	}
+	if p.X() == 0 {
+		ret = append(ret, core.W)
+	}
+	return ret
+}
+
+// setTile gives a blank tile the kind of a path through it, tiles already on a path are left alone
+func (g BasicGraph) setTile(p core.Point, entry, exit core.Direction, aa asset.AssetAtlas) *Node {
+	if nd := g.Node(p); !nd.IsBlank() {
+		return nd
+	}
+	k, terrain := core.DirectionsToKind(entry, exit), g.Node(p).terrain
+	g[p.Y()][p.X()] = Nd(0, p, k, aa.Asset(k))
+	g.Node(p).terrain = terrain
+	return g.Node(p)
+}
+
+// addPath lays path i of n onto the graph, tiles already on an earlier path keep that path's asset
+func (g BasicGraph) addPath(i, n int, start, end core.Point, dirs []core.Direction, aa asset.AssetAtlas) *Path {
+	first, last := core.S, core.S
+	if len(dirs) > 0 {
+		first, last = dirs[0], dirs[len(dirs)-1]
+	}
+	ret := &Path{start, end, borderDirection(first, g.entries(start)...), borderDirection(last, g.exits(end)...), nil, nil, 0}
+	dirs = append(append([]core.Direction{ret.Entry}, dirs...), ret.Exit)
+	p, kinds := start, make([]core.Kind, 0, len(dirs))
+	for j := 1; j < len(dirs); j++ {
+		kinds = append(kinds, core.DirectionsToKind(dirs[j-1], dirs[j]))
+		nd := g.setTile(p, dirs[j-1], dirs[j], aa)
+		if nd.distances == nil {
+			nd.distances = make([]int, n)
+			for m := range nd.distances {
+				nd.distances[m] = -1
+			}
+		}
+		if nd.distances[i] < 0 {
+			nd.distances[i] = len(dirs) - j
+		}
+		p = p.Neighbor(dirs[j])
+	}
+	ret.kinds = append(kinds, core.DirectionsToKind(ret.Exit, ret.Exit))
+	return ret
 }
 
 func (g BasicGraph) Process(ticks int, con core.Context) bool {
@@ -376,7 +698,7 @@ func (g BasicGraph) Node(p core.Point) *Node {
 }
 
 func (g BasicGraph) Neighbors(n Node) []NodeDirection {
-	ret := make([]NodeDirection, 0)
+	ret := make([]NodeDirection, 0, len(core.Directions))
 	for _, d := range core.Directions {
 		p := n.Point.Neighbor(d)
 		if g.Contains(p) {
@@ -386,8 +708,9 @@ func (g BasicGraph) Neighbors(n Node) []NodeDirection {
 	return ret
 }
 
-func (g BasicGraph) Size() (int, int) {
-	return g.Width() * core.TileSizeInt, g.Height() * core.TileSizeInt
+// Size is the size of the map in pixels
+func (g CachedImageGraph) Size() (int, int) {
+	return g.Width() * g.TileSize, g.Height() * g.TileSize
 }
 
 func (g BasicGraph) String() string {
@@ -402,11 +725,15 @@ func (g BasicGraph) String() string {
 	return sb.String()
 }
 
-func (g BasicGraph) TilesAround(p core.Point, rng core.Range) []*Node {
-	tp, ret := p.TileIndex(), make([]*Node, 0)
+func (g CachedImageGraph) TilesAround(p core.Point, rng core.Range) []*Node {
+	tp, ret := p.TileIndex(g.TileSize), make([]*Node, 0)
 	for dist := rng.Min; dist < rng.Max; dist++ {
 		for i := -dist; i <= dist; i++ {
 			for j := -dist; j <= dist; j++ {
+				if dist > rng.Min && core.MaxInt(core.AbsInt(i), core.AbsInt(j)) != dist {
+					// only the ring at dist, the tiles inside of it were added by earlier iterations
+					continue
+				}
 				ntp := tp.Add(core.Pt(i, j))
 				if nd := g.Node(ntp); nd != nil {
 					ret = append(ret, nd)
@@ -418,34 +745,141 @@ func (g BasicGraph) TilesAround(p core.Point, rng core.Range) []*Node {
 	return ret
 }
 
-func (g BasicGraph) Draw(con *gg.Context) {
+// NodesWithin returns every node overlapped by the square bounding the circle of radius pixels around p.
+func (g CachedImageGraph) NodesWithin(p core.Point, radius int) []*Node {
+	rad := core.Pt(radius, radius)
+	min, max := p.Subtract(rad).TileIndex(g.TileSize), p.Add(rad).TileIndex(g.TileSize)
+	ret := make([]*Node, 0)
+	for y := min.Y(); y <= max.Y(); y++ {
+		for x := min.X(); x <= max.X(); x++ {
+			if nd := g.Node(core.Pt(x, y)); nd != nil {
+				ret = append(ret, nd)
+			}
+		}
+	}
+	return ret
+}
+
+// DamageablesWithin returns each Damageable whose center is within radius pixels of p, only looking
+// at the tiles that the radius overlaps.
+func (g CachedImageGraph) DamageablesWithin(p core.Point, radius int) []Damageable {
+	seen, ret := make(map[Damageable]core.Flag), make([]Damageable, 0)
+	for _, nd := range g.NodesWithin(p, radius) {
+		for _, d := range nd.dables {
+			if _, ok := seen[d]; ok {
+				continue
+			}
+			seen[d] = core.On
+			if d.Center().Near(p, radius) {
+				ret = append(ret, d)
+			}
+		}
+	}
+	return ret
+}
+
+// sizeAssets gives the graph its own copies of the assets of its nodes centered in tiles tile pixels wide, nodes
+// that shared an asset keep sharing the copy
+func (g BasicGraph) sizeAssets(tile int) {
+	copies := make(map[asset.Asset]asset.Asset)
 	for _, row := range g {
-		for _, n := range row {
-			n.Draw(con)
+		for _, nd := range row {
+			if nd.a == nil {
+				continue
+			}
+			if _, ok := copies[nd.a]; !ok {
+				copies[nd.a] = nd.a.Copy()
+				copies[nd.a].SetTileSize(tile)
+			}
+			nd.a = copies[nd.a]
 		}
 	}
+}
 
+// Draw draws the graph with tiles tile pixels wide, graphs that are not cached are drawn this way
+func (g BasicGraph) Draw(con *gg.Context, tile int) {
+	g.drawNodes(con, tile)
 	if core.Grid {
-		minY, maxY := core.Zero, core.TileIndexToCoordinate(g.Height())
-		for x := 0; x <= g.Width(); x++ {
-			xCoord := core.TileIndexToCoordinate(x)
-			con.DrawLine(xCoord, minY, xCoord, float64(maxY))
+		g.drawGrid(con, tile)
+	}
+}
+
+func (g BasicGraph) drawNodes(con *gg.Context, tile int) {
+	for _, row := range g {
+		for _, n := range row {
+			n.Draw(con, tile)
 		}
+	}
+}
+
+func (g BasicGraph) drawGrid(con *gg.Context, tile int) {
+	minY, maxY := core.Zero, core.TileIndexToCoordinate(g.Height(), tile)
+	for x := 0; x <= g.Width(); x++ {
+		xCoord := core.TileIndexToCoordinate(x, tile)
+		con.DrawLine(xCoord, minY, xCoord, float64(maxY))
+	}
+
+	minX, maxX := core.Zero, core.TileIndexToCoordinate(g.Width(), tile)
+	for y := 0; y <= g.Height(); y++ {
+		yCoord := core.TileIndexToCoordinate(y, tile)
+		con.DrawLine(minX, yCoord, maxX, yCoord)
+	}
+
+	con.SetLineWidth(2)
+	con.SetColor(color.Black)
+	con.Stroke()
+}
+
+func (g CachedImageGraph) Paths() []*Path {
+	return g.paths
+}
+
+func (g CachedImageGraph) Path(i int) *Path {
+	return g.paths[i]
+}
+
+// Segments are every run between forks of the map, a segment's ID is its index
+func (g CachedImageGraph) Segments() []*Segment {
+	return g.segments
+}
+
+// StartLocs is where enemies spawn for each path
+func (g CachedImageGraph) StartLocs() []core.Location {
+	ret := make([]core.Location, len(g.paths))
+	for i, p := range g.paths {
+		ret[i] = p.StartLoc()
+	}
+	return ret
+}
+
+// Flow is the flow field to the exit of path i
+func (g CachedImageGraph) Flow(i int) *FlowField {
+	return g.flows[i]
+}
 
-		minX, maxX := core.Zero, core.TileIndexToCoordinate(g.Width())
-		for y := 0; y <= g.Height(); y++ {
-			yCoord := core.TileIndexToCoordinate(y)
-			con.DrawLine(minX, yCoord, maxX, yCoord)
+// Flows are the flow fields of every exit of the map
+func (g CachedImageGraph) Flows() []*FlowField {
+	ret := make([]*FlowField, 0, len(g.flows))
+	for _, f := range g.flows {
+		if !containsFlow(ret, f) {
+			ret = append(ret, f)
 		}
+	}
+	return ret
+}
 
-		con.SetLineWidth(2)
-		con.SetColor(color.Black)
-		con.Stroke()
+func containsFlow(fs []*FlowField, f *FlowField) bool {
+	for _, o := range fs {
+		if o == f {
+			return true
+		}
 	}
+	return false
 }
 
-func (g CachedImageGraph) Path() []core.Kind {
-	return g.path
+// Maze is the open field of a maze map, nil on maps with paths
+func (g CachedImageGraph) Maze() *Maze {
+	return g.maze
 }
 
 func (g CachedImageGraph) Spec() *GraphSpec {
@@ -463,24 +897,28 @@ func (g CachedImageGraph) Draw(con *gg.Context) {
 	}
 }
 
-func (g CachedImageGraph) InitialRotation() int {
-	rot := 0 /// SSS is first dir
-	if g.path[0] == core.EE {
-		rot = core.CounterClockwise(rot, 90)
-	}
-	return rot
+func (p *Path) Kinds() []core.Kind {
+	return p.kinds
 }
 
-func (g CachedImageGraph) InitialPoint() core.Point {
-	ret := g.start
-	if g.path[0] == core.SS {
-		ret = ret.Add(core.Pt(0, -1))
-	} else {
-		ret = ret.Add(core.Pt(-1, 0))
-	}
-	return ret
+func (p *Path) InitialRotation() int {
+	return p.Entry.Rotation()
+}
+
+// InitialPoint is the tile just before the start of the path that enemies enter it from
+func (p *Path) InitialPoint() core.Point {
+	return p.Start.Neighbor(p.Entry.Opposite())
+}
+
+func (p *Path) StartLoc() core.Location {
+	return core.Loc(p.InitialPoint().Scale(p.tile), p.InitialRotation())
+}
+
+// FinalPoint is the tile just past the end of the path that enemies leave into
+func (p *Path) FinalPoint() core.Point {
+	return p.End.Neighbor(p.Exit)
 }
 
-func (g CachedImageGraph) StartLoc() core.Location {
-	return core.Loc(g.InitialPoint().Scale(core.TileSizeInt), g.InitialRotation())
+func (p *Path) EndLoc() core.Location {
+	return core.Loc(p.FinalPoint().Scale(p.tile), p.Exit.Rotation())
 }
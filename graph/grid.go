@@ -0,0 +1,417 @@
+package graph
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strings"
+	"tdgame/asset"
+	"tdgame/core"
+)
+
+type (
+	// PlacedTower is a tower the map places before the game starts
+	PlacedTower struct {
+		core.Point
+		Kind core.Kind
+	}
+	// GridTile is what a character of a grid map stands for, Arg is the kind of tower for towers and the
+	// terrain under the road for path, spawn and exit tiles
+	GridTile struct {
+		Meaning core.Kind
+		Arg     core.Kind
+	}
+	// gridMap is a parsed grid map file, tiles are indexed by row then column
+	gridMap struct {
+		file          string
+		tiles         [][]GridTile
+		spawns, exits []core.Point
+	}
+)
+
+const (
+	GridVariety = "grid"
+	// LegendHeader starts the legend of a grid map, every line after it is a character followed by its meaning
+	LegendHeader = "legend"
+	// GridHeader starts the rows of a grid map
+	GridHeader = "grid"
+	// Meanings of the characters of a grid map
+	PathTile  core.Kind = "path"
+	SpawnTile core.Kind = "spawn"
+	ExitTile  core.Kind = "exit"
+	TowerTile core.Kind = "tower"
+)
+
+// gridChars are the characters WriteGrid gives the most common tiles
+var gridChars = map[GridTile]rune{
+	{Grass, ""}:     '.',
+	{Water, ""}:     '~',
+	{Rock, ""}:      '^',
+	{PathTile, ""}:  '#',
+	{SpawnTile, ""}: 'S',
+	{ExitTile, ""}:  'X',
+}
+
+// GridFromSpec reads a grid map, the kinds of the path tiles come from the path tiles around them. Every spawn
+// starts a path that follows the road straight through crossings until it reaches an exit, path tiles that are
+// not on any of those paths become branches.
+func GridFromSpec(spec *GraphSpec, aa asset.AssetAtlas) CachedImageGraph {
+	data, err := ioutil.ReadFile(path.Join(spec.FilePath, spec.File))
+	core.Check(err)
+	return GridFromData(spec, string(data), aa)
+}
+
+// GridFromData is GridFromSpec for a grid map that is not in a file
+func GridFromData(spec *GraphSpec, data string, aa asset.AssetAtlas) CachedImageGraph {
+	return parseGrid(spec, data).graph(spec, aa)
+}
+
+// graph lays the paths of the map onto a graph with its terrain and collects the towers it places
+func (gm *gridMap) graph(spec *GraphSpec, aa asset.AssetAtlas) CachedImageGraph {
+	g := NewGraph(len(gm.tiles[0]), len(gm.tiles))
+	for y, row := range gm.tiles {
+		for x, t := range row {
+			if k := t.Terrain(); k != "" {
+				g.Node(core.Pt(x, y)).terrain = k
+			}
+		}
+	}
+	dist := gm.distances()
+	covered := make(map[core.Point]bool)
+	paths := make([][]core.Direction, len(gm.spawns))
+	for i, s := range gm.spawns {
+		paths[i] = gm.walk(s, gm.heading(g, s, dist), dist, nil, covered)
+	}
+	branches := make([]Branch, 0)
+	for {
+		from, d, ok := gm.attachment(dist, covered)
+		if !ok {
+			break
+		}
+		branches = append(branches, Branch{from, 1, append([]core.Direction{d}, gm.walk(from.Neighbor(d), d, dist, covered, covered)...)})
+	}
+	for y, row := range gm.tiles {
+		for x := range row {
+			if gm.isPath(core.Pt(x, y)) && !covered[core.Pt(x, y)] {
+				gm.fail(y, x, "path tile is not connected to a spawn")
+			}
+		}
+	}
+	ps, segments := g.layPaths(gm.spawns, paths, branches, aa)
+	ret := cache(spec, g, ps, segments, PathCost, aa)
+	for y, row := range gm.tiles {
+		for x, t := range row {
+			if t.Meaning == TowerTile {
+				ret.towers = append(ret.towers, PlacedTower{core.Pt(x, y), t.Arg})
+			}
+		}
+	}
+	return ret
+}
+
+func parseGrid(spec *GraphSpec, data string) *gridMap {
+	gm := readGrid(spec, data)
+	if len(gm.tiles) == 0 {
+		panic(fmt.Sprintf("map %s: map has no %s", gm.file, GridHeader))
+	}
+	if len(gm.spawns) == 0 || len(gm.exits) == 0 {
+		panic(fmt.Sprintf("map %s: map must have at least 1 spawn and 1 exit", gm.file))
+	}
+	return gm
+}
+
+// ReadGrid reads the tiles of a grid map by row then column, unlike GridFromData it only checks that the
+// characters are in the legend and that the rows line up
+func ReadGrid(spec *GraphSpec, data string) [][]GridTile {
+	return readGrid(spec, data).tiles
+}
+
+func readGrid(spec *GraphSpec, data string) *gridMap {
+	file := spec.File
+	gm := &gridMap{file: file}
+	legend, section := make(map[rune]GridTile), ""
+	for i, line := range strings.Split(data, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" {
+			continue
+		}
+		if line == LegendHeader || line == GridHeader {
+			section = line
+			continue
+		}
+		switch section {
+		case LegendHeader:
+			fields := strings.Fields(line)
+			if len([]rune(fields[0])) != 1 || len(fields) < 2 {
+				panic(fmt.Sprintf("map %s line %d: legend must be a single character followed by its meaning", file, i+1))
+			}
+			e := GridTile{core.Kind(fields[1]), ""}
+			switch e.Meaning {
+			case PathTile, SpawnTile, ExitTile:
+				if len(fields) > 2 {
+					if _, ok := spec.Terrain(core.Kind(fields[2])); !ok {
+						panic(fmt.Sprintf("map %s line %d: terrain %s under the %s does not exist", file, i+1, fields[2], e.Meaning))
+					}
+					e.Arg = core.Kind(fields[2])
+				}
+			case TowerTile:
+				if len(fields) < 3 {
+					panic(fmt.Sprintf("map %s line %d: tower in legend must be followed by the kind of tower", file, i+1))
+				}
+				e.Arg = core.Kind(fields[2])
+			default:
+				if _, ok := spec.Terrain(e.Meaning); !ok {
+					panic(fmt.Sprintf("map %s line %d: meaning %s of legend does not exist", file, i+1, e.Meaning))
+				}
+			}
+			legend[[]rune(fields[0])[0]] = e
+		case GridHeader:
+			y, row := len(gm.tiles), make([]GridTile, 0, len(line))
+			for x, c := range []rune(line) {
+				e, ok := legend[c]
+				if !ok {
+					gm.fail(y, x, fmt.Sprintf("character %q is not in the legend", c))
+				}
+				switch e.Meaning {
+				case SpawnTile:
+					gm.spawns = append(gm.spawns, core.Pt(x, y))
+				case ExitTile:
+					gm.exits = append(gm.exits, core.Pt(x, y))
+				}
+				row = append(row, e)
+			}
+			if y > 0 && len(row) != len(gm.tiles[0]) {
+				gm.fail(y, core.MinInt(len(row), len(gm.tiles[0])), fmt.Sprintf("row has %d columns but the first row has %d", len(row), len(gm.tiles[0])))
+			}
+			gm.tiles = append(gm.tiles, row)
+		default:
+			panic(fmt.Sprintf("map %s line %d: map must start with %s", file, i+1, LegendHeader))
+		}
+	}
+	return gm
+}
+
+// WriteGrid writes tiles in the grid map format, every kind of tile gets a character of the legend in the order
+// they first appear
+func WriteGrid(tiles [][]GridTile) string {
+	legend, used, sb := make(map[GridTile]rune), make(map[rune]bool), strings.Builder{}
+	sb.WriteString(LegendHeader + "\n")
+	for _, row := range tiles {
+		for _, t := range row {
+			if _, ok := legend[t]; ok {
+				continue
+			}
+			c, ok := gridChars[t]
+			if !ok || used[c] {
+				for c = 'a'; used[c]; c++ {
+				}
+			}
+			legend[t], used[c] = c, true
+			sb.WriteString(strings.TrimSpace(fmt.Sprintf("%c %s %s", c, t.Meaning, t.Arg)) + "\n")
+		}
+	}
+	sb.WriteString("\n" + GridHeader + "\n")
+	for _, row := range tiles {
+		for _, t := range row {
+			sb.WriteRune(legend[t])
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// IsPath reports whether enemies walk on the tile
+func (t GridTile) IsPath() bool {
+	switch t.Meaning {
+	case PathTile, SpawnTile, ExitTile:
+		return true
+	}
+	return false
+}
+
+// Terrain is the terrain of the tile, the one under the road for path tiles and none for towers
+func (t GridTile) Terrain() core.Kind {
+	switch {
+	case t.IsPath():
+		return t.Arg
+	case t.Meaning == TowerTile:
+		return ""
+	default:
+		return t.Meaning
+	}
+}
+
+// PreviewGrid draws tiles the way their map would look without checking that the path leads anywhere, path tiles
+// join the path tiles next to them
+func PreviewGrid(spec *GraphSpec, tiles [][]GridTile, aa asset.AssetAtlas) BasicGraph {
+	g := NewGraph(len(tiles[0]), len(tiles))
+	for y, row := range tiles {
+		for x, t := range row {
+			if k := t.Terrain(); k != "" {
+				g.Node(core.Pt(x, y)).terrain = k
+			}
+		}
+	}
+	spec.applyTerrain(g, aa)
+	for y, row := range tiles {
+		for x, t := range row {
+			if !t.IsPath() {
+				continue
+			}
+			open, p := make([]core.Direction, 0, len(core.Directions)), core.Pt(x, y)
+			for _, d := range core.Directions {
+				if n := p.Neighbor(d); g.Contains(n) && tiles[n.Y()][n.X()].IsPath() {
+					open = append(open, d)
+				}
+			}
+			nd := g.Node(p)
+			nd.k = joinKind(open)
+			nd.a = aa[nd.k]
+		}
+	}
+	g.sizeAssets(spec.TileSize)
+	return g
+}
+
+// joinKind is the kind of path tile open on the sides in open
+func joinKind(open []core.Direction) core.Kind {
+	switch len(open) {
+	case 0:
+		return core.NN
+	case 1:
+		return core.DirectionsToKind(open[0], open[0])
+	case 2:
+		return core.DirectionsToKind(open[0].Opposite(), open[1])
+	default:
+		return core.JunctionKind(open)
+	}
+}
+
+// fail reports a problem with the tile at row y and column x, both counted from 1 in the message
+func (gm *gridMap) fail(y, x int, msg string) {
+	panic(fmt.Sprintf("map %s row %d column %d: %s", gm.file, y+1, x+1, msg))
+}
+
+func (gm *gridMap) contains(p core.Point) bool {
+	return p.Y() >= 0 && p.Y() < len(gm.tiles) && p.X() >= 0 && p.X() < len(gm.tiles[p.Y()])
+}
+
+func (gm *gridMap) isPath(p core.Point) bool {
+	return gm.contains(p) && gm.tiles[p.Y()][p.X()].IsPath()
+}
+
+func (gm *gridMap) terrain(p core.Point) core.Kind {
+	return gm.tiles[p.Y()][p.X()].Terrain()
+}
+
+func (gm *gridMap) isExit(p core.Point) bool {
+	return gm.contains(p) && gm.tiles[p.Y()][p.X()].Meaning == ExitTile
+}
+
+// distances are the fewest path tiles from every path tile to an exit
+func (gm *gridMap) distances() map[core.Point]int {
+	ret, queue := make(map[core.Point]int), append([]core.Point{}, gm.exits...)
+	for _, e := range gm.exits {
+		ret[e] = 0
+	}
+	for i := 0; i < len(queue); i++ {
+		for _, d := range core.Directions {
+			n := queue[i].Neighbor(d)
+			if _, ok := ret[n]; !ok && gm.isPath(n) {
+				ret[n] = ret[queue[i]] + 1
+				queue = append(queue, n)
+			}
+		}
+	}
+	return ret
+}
+
+// heading is the direction enemies enter a spawn moving in, through the border it is on or toward the path
+// tile next to it that is closest to an exit
+func (gm *gridMap) heading(g BasicGraph, s core.Point, dist map[core.Point]int) core.Direction {
+	if ins := g.entries(s); len(ins) > 0 {
+		return ins[0]
+	}
+	ret, best := core.Direction(0), -1
+	for _, d := range core.Directions {
+		if n := s.Neighbor(d); gm.isPath(n) {
+			if dn, ok := dist[n]; ok && (best < 0 || dn < best) {
+				ret, best = d, dn
+			}
+		}
+	}
+	if ret == 0 {
+		gm.fail(s.Y(), s.X(), "spawn has no path tile next to it that leads to an exit")
+	}
+	return ret
+}
+
+// walk follows the road from p entered moving in heading until it reaches an exit or, when stop is given, a
+// tile in stop. It goes straight where it can, otherwise it turns toward the tile closest to an exit and
+// prefers tiles no path has covered yet. The tiles it walks are added to covered.
+func (gm *gridMap) walk(p core.Point, heading core.Direction, dist map[core.Point]int, stop, covered map[core.Point]bool) []core.Direction {
+	dirs, limit := make([]core.Direction, 0), len(gm.tiles)*len(gm.tiles[0])*4
+	for {
+		if stop[p] {
+			return dirs
+		}
+		covered[p] = true
+		if gm.isExit(p) {
+			return dirs
+		}
+		next, score := core.Direction(0), 0
+		for _, d := range core.Directions {
+			n := p.Neighbor(d)
+			if d == heading.Opposite() || !gm.isPath(n) {
+				continue
+			}
+			// branches take tiles no path has covered first, then every walk goes straight and then closer to
+			// an exit
+			sc, l := 0, len(dist)
+			if dn, ok := dist[n]; ok {
+				sc += 4*l - dn
+			}
+			if d == heading {
+				sc += 16 * l
+			}
+			if stop != nil && !covered[n] {
+				sc += 64 * l
+			}
+			if next == 0 || sc > score {
+				next, score = d, sc
+			}
+		}
+		if next == 0 {
+			gm.fail(p.Y(), p.X(), "path leads nowhere, it must end on an exit")
+		}
+		if len(dirs) > limit {
+			gm.fail(p.Y(), p.X(), "path loops without reaching an exit")
+		}
+		dirs, p, heading = append(dirs, next), p.Neighbor(next), next
+	}
+}
+
+// attachment is a covered tile next to a path tile no path has covered, the one farthest from an exit so that
+// branches lead toward the exits
+func (gm *gridMap) attachment(dist map[core.Point]int, covered map[core.Point]bool) (core.Point, core.Direction, bool) {
+	from, dir, best := core.ZeroPt, core.Direction(0), -1
+	for y, row := range gm.tiles {
+		for x := range row {
+			p := core.Pt(x, y)
+			if !covered[p] || gm.isExit(p) {
+				continue
+			}
+			for _, d := range core.Directions {
+				if n := p.Neighbor(d); gm.isPath(n) && !covered[n] && dist[p] > best {
+					from, dir, best = p, d, dist[p]
+				}
+			}
+		}
+	}
+	return from, dir, best >= 0
+}
+
+// Towers are the towers the map places before the game starts
+func (g CachedImageGraph) Towers() []PlacedTower {
+	return g.towers
+}
@@ -0,0 +1,25 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestReadGridErrors(t *testing.T) {
+	for _, tc := range []struct {
+		grid, want string
+	}{
+		{"S#?\n..X", "row 1 column 3: character '?' is not in the legend"},
+		{"S#.\n.X", "row 2 column 3: row has 2 columns but the first row has 3"},
+	} {
+		func() {
+			defer func() {
+				if got := fmt.Sprint(recover()); !strings.Contains(got, tc.want) {
+					t.Errorf("%q: got %q, want %q", tc.grid, got, tc.want)
+				}
+			}()
+			ReadGrid(&GraphSpec{}, "legend\n. grass\n# path\nS spawn\nX exit\n\ngrid\n"+tc.grid)
+		}()
+	}
+}
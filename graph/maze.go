@@ -0,0 +1,431 @@
+package graph
+
+import (
+	"tdgame/asset"
+	"tdgame/core"
+)
+
+type (
+	// Maze is an open field without a path, enemies find their own way from a spawn to the closest exit and
+	// towers block the tiles they are built on. Blocks and clears both change the version of the maze, clears
+	// also change its cleared version since they can open up a shorter way for every enemy.
+	Maze struct {
+		BasicGraph
+		Spawns, Exits    []core.Point
+		blocked          []bool
+		version, cleared int
+		flows            []*FlowField // fields that are kept up to date as tiles are blocked and cleared
+		// routes are cached by the tile they start from until a change to the maze makes them longer than
+		// they have to be or walks them through a blocked tile
+		routes map[core.Point][]core.Point
+		// search buffers reused by every route, a tile is only valid for the search its stamp matches
+		stamp          int
+		stamps, scores []int
+		from           []int
+		open           openSet
+	}
+	// Flier is implemented by damageables that can fly over blocked tiles
+	Flier interface {
+		Flying() bool
+	}
+	openTile struct {
+		idx, f int
+	}
+	openSet []openTile
+)
+
+const (
+	MazeVariety = "maze"
+)
+
+// push adds a tile to the binary heap of the open set, the heap is kept by hand since container/heap would
+// allocate for every tile
+func (o *openSet) push(t openTile) {
+	*o = append(*o, t)
+	h := *o
+	for i := len(h) - 1; i > 0; {
+		parent := (i - 1) / 2
+		if h[parent].f <= h[i].f {
+			break
+		}
+		h[parent], h[i] = h[i], h[parent]
+		i = parent
+	}
+}
+
+// pop removes the tile with the lowest score
+func (o *openSet) pop() openTile {
+	h := *o
+	ret, last := h[0], len(h)-1
+	h[0] = h[last]
+	h = h[:last]
+	for i := 0; ; {
+		min, l, r := i, 2*i+1, 2*i+2
+		if l < len(h) && h[l].f < h[min].f {
+			min = l
+		}
+		if r < len(h) && h[r].f < h[min].f {
+			min = r
+		}
+		if min == i {
+			break
+		}
+		h[min], h[i] = h[i], h[min]
+		i = min
+	}
+	*o = h
+	return ret
+}
+
+func NewMaze(g BasicGraph, spawns, exits []core.Point) *Maze {
+	if len(spawns) == 0 || len(exits) == 0 {
+		panic("maze must have at least 1 spawn and 1 exit")
+	}
+	n := g.Width() * g.Height()
+	m := &Maze{
+		g,
+		spawns,
+		exits,
+		make([]bool, n),
+		0,
+		0,
+		nil,
+		make(map[core.Point][]core.Point),
+		0,
+		make([]int, n),
+		make([]int, n),
+		make([]int, n),
+		make(openSet, 0, n),
+	}
+	for _, p := range append(append([]core.Point{}, spawns...), exits...) {
+		if !g.Contains(p) {
+			panic("spawns and exits of a maze must be on the map")
+		}
+	}
+	for _, s := range spawns {
+		if _, ok := m.Route(s); !ok {
+			panic("spawn of maze cannot reach an exit")
+		}
+	}
+	return m
+}
+
+// MazeFromSpec creates an empty field of the size of the spec, spawns and exits are marked with path tiles
+func MazeFromSpec(spec *GraphSpec, aa asset.AssetAtlas) CachedImageGraph {
+	g := NewGraph(spec.Width, spec.Height)
+	spawns, exits := parsePoints(spec.Spawns), parsePoints(spec.Exits)
+	m := NewMaze(g, spawns, exits)
+	paths := make([]*Path, len(spawns))
+	for i, s := range spawns {
+		route, _ := m.Route(s)
+		end := route[len(route)-1]
+		if !g.Contains(end) {
+			end = route[len(route)-2]
+		}
+		paths[i] = &Path{s, end, borderDirection(core.S, g.entries(s)...), borderDirection(core.S, g.exits(end)...), nil, nil, 0}
+		g.setTile(s, paths[i].Entry, paths[i].Entry, aa)
+	}
+	for _, e := range exits {
+		d := borderDirection(core.S, g.exits(e)...)
+		g.setTile(e, d, d, aa)
+	}
+	ret := cache(spec, g, paths, nil, m.Cost, aa)
+	ret.maze, m.flows = m, ret.Flows()
+	return ret
+}
+
+func parsePoints(ss []string) []core.Point {
+	ret := make([]core.Point, len(ss))
+	for i, s := range ss {
+		p, ok := parsePoint(s)
+		if !ok {
+			panic("point of maze must be x,y")
+		}
+		ret[i] = p
+	}
+	return ret
+}
+
+func (m *Maze) idx(p core.Point) int {
+	return p.Y()*m.Width() + p.X()
+}
+
+func (m *Maze) Blocked(p core.Point) bool {
+	return m.Contains(p) && m.blocked[m.idx(p)]
+}
+
+// Cost is the cost of walking on a node for the flow fields of the maze
+func (m *Maze) Cost(nd *Node) int {
+	if m.Blocked(nd.Point) {
+		return Impassable
+	}
+	return nd.mods.cost()
+}
+
+// Walkable reports whether enemies can walk on the tile
+func (m *Maze) Walkable(p core.Point) bool {
+	return m.Contains(p) && !m.blocked[m.idx(p)]
+}
+
+func (m *Maze) Version() (version, cleared int) {
+	return m.version, m.cleared
+}
+
+func (m *Maze) isExit(p core.Point) bool {
+	for _, e := range m.Exits {
+		if e == p {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Maze) isSpawn(p core.Point) bool {
+	for _, s := range m.Spawns {
+		if s == p {
+			return true
+		}
+	}
+	return false
+}
+
+// heuristic is the manhattan distance to the closest exit
+func (m *Maze) heuristic(p core.Point) int {
+	ret := -1
+	for _, e := range m.Exits {
+		if d := core.AbsInt(e.X()-p.X()) + core.AbsInt(e.Y()-p.Y()); ret < 0 || d < ret {
+			ret = d
+		}
+	}
+	return ret
+}
+
+// Route finds the shortest way from a tile to the closest exit with A*, the route starts with from and ends
+// with the tile past the exit that leaves the map. Routes are shared so they must not be modified.
+func (m *Maze) Route(from core.Point) ([]core.Point, bool) {
+	if r, ok := m.routes[from]; ok {
+		return r, r != nil
+	}
+	r := m.search(from)
+	m.routes[from] = r
+	// every tile of a shortest route is also on a shortest route from that tile
+	for i, p := range r {
+		if _, ok := m.routes[p]; !ok && m.Contains(p) {
+			m.routes[p] = r[i:]
+		}
+	}
+	return r, r != nil
+}
+
+func (m *Maze) search(from core.Point) []core.Point {
+	if !m.Walkable(from) {
+		return nil
+	}
+	m.stamp++
+	m.open = m.open[:0]
+	start := m.idx(from)
+	m.stamps[start], m.scores[start], m.from[start] = m.stamp, 0, -1
+	m.open.push(openTile{start, m.heuristic(from)})
+	w := m.Width()
+	for len(m.open) > 0 {
+		cur := m.open.pop()
+		p := core.Pt(cur.idx%w, cur.idx/w)
+		if cur.f > m.scores[cur.idx]+m.heuristic(p) {
+			// a shorter way to the tile was found after this one was queued
+			continue
+		}
+		if m.isExit(p) {
+			return m.trace(cur.idx)
+		}
+		for _, d := range core.Directions {
+			n := p.Neighbor(d)
+			if !m.Walkable(n) {
+				continue
+			}
+			ni, score := m.idx(n), m.scores[cur.idx]+1
+			if m.stamps[ni] == m.stamp && m.scores[ni] <= score {
+				continue
+			}
+			m.stamps[ni], m.scores[ni], m.from[ni] = m.stamp, score, cur.idx
+			m.open.push(openTile{ni, score + m.heuristic(n)})
+		}
+	}
+	return nil
+}
+
+func (m *Maze) trace(end int) []core.Point {
+	w, n := m.Width(), m.scores[end]+1
+	ret := make([]core.Point, n, n+1)
+	for i, cur := n-1, end; cur >= 0; i, cur = i-1, m.from[cur] {
+		ret[i] = core.Pt(cur%w, cur/w)
+	}
+	exit := ret[n-1]
+	if outs := m.exits(exit); len(outs) > 0 {
+		ret = append(ret, exit.Neighbor(outs[0]))
+	}
+	return ret
+}
+
+// SpawnRoute is the route from spawn i, starting from the tile enemies enter the spawn from
+func (m *Maze) SpawnRoute(i int) []core.Point {
+	s := m.Spawns[i]
+	route, _ := m.Route(s)
+	return append([]core.Point{s.Neighbor(borderDirection(core.S, m.entries(s)...).Opposite())}, route...)
+}
+
+// CanBlock reports whether a tower can be built on the tile, it cannot be built on a spawn, an exit or a
+// walking enemy, or where it would leave a spawn or a walking enemy without a way to an exit. Blocking a tile
+// can only cut off tiles whose way out goes through it, so the whole maze is only flooded when one of the open
+// neighbors of the tile loses its way to an exit.
+func (m *Maze) CanBlock(p core.Point) bool {
+	if !m.Walkable(p) || m.isSpawn(p) || m.isExit(p) || m.walkers(m.Node(p)) {
+		return false
+	}
+	m.blocked[m.idx(p)] = true
+	defer func() { m.blocked[m.idx(p)] = false }()
+	for _, d := range core.Directions {
+		n := p.Neighbor(d)
+		r, ok := m.routes[n]
+		if ok && !onRoute(r, p) {
+			continue
+		}
+		if m.search(n) == nil && m.Walkable(n) {
+			return m.reachable()
+		}
+	}
+	return true
+}
+
+// reachable floods the maze from the exits and reports whether every spawn and walking enemy was reached
+func (m *Maze) reachable() bool {
+	m.flood(m.Exits...)
+	for _, s := range m.Spawns {
+		if m.stamps[m.idx(s)] != m.stamp {
+			return false
+		}
+	}
+	w := m.Width()
+	for y, row := range m.BasicGraph {
+		for x, nd := range row {
+			// enemies overlap the blocked tiles next to the one they walk on
+			if !m.blocked[y*w+x] && m.walkers(nd) && m.stamps[y*w+x] != m.stamp {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// flood stamps every walkable tile that can be reached from the tiles with its distance to the closest of them
+func (m *Maze) flood(from ...core.Point) {
+	m.stamp++
+	queue := m.open[:0]
+	for _, p := range from {
+		queue = append(queue, openTile{m.idx(p), 0})
+		m.stamps[m.idx(p)], m.scores[m.idx(p)] = m.stamp, 0
+	}
+	w := m.Width()
+	for i := 0; i < len(queue); i++ {
+		cur := core.Pt(queue[i].idx%w, queue[i].idx/w)
+		for _, d := range core.Directions {
+			n := cur.Neighbor(d)
+			if m.Walkable(n) && m.stamps[m.idx(n)] != m.stamp {
+				m.stamps[m.idx(n)], m.scores[m.idx(n)] = m.stamp, m.scores[queue[i].idx]+1
+				queue = append(queue, openTile{m.idx(n), 0})
+			}
+		}
+	}
+	m.open = queue[:0]
+}
+
+func onRoute(route []core.Point, p core.Point) bool {
+	for _, r := range route {
+		if r == p {
+			return true
+		}
+	}
+	return false
+}
+
+// steps is the number of steps a route takes to its exit
+func (m *Maze) steps(route []core.Point) int {
+	if !m.Contains(route[len(route)-1]) {
+		return len(route) - 2
+	}
+	return len(route) - 1
+}
+
+// walkers reports whether an enemy that does not fly is on the node
+func (m *Maze) walkers(nd *Node) bool {
+	for _, d := range nd.dables {
+		if f, ok := d.(Flier); !ok || !f.Flying() {
+			return true
+		}
+	}
+	return false
+}
+
+// Block marks the tile as blocked if CanBlock allows it
+func (m *Maze) Block(p core.Point) bool {
+	if !m.CanBlock(p) {
+		return false
+	}
+	m.blocked[m.idx(p)] = true
+	m.version++
+	// blocking a tile makes no way shorter, only the routes through it have to be found again
+	for from, r := range m.routes {
+		if onRoute(r, p) {
+			delete(m.routes, from)
+		}
+	}
+	for _, f := range m.flows {
+		f.SetCost(p, Impassable)
+	}
+	return true
+}
+
+// Clear opens a blocked tile back up
+func (m *Maze) Clear(p core.Point) {
+	if !m.Blocked(p) {
+		return
+	}
+	m.blocked[m.idx(p)] = false
+	m.version++
+	m.cleared++
+	for _, f := range m.flows {
+		f.SetCost(p, m.Cost(m.Node(p)))
+	}
+	for from, r := range m.routes {
+		if r == nil {
+			delete(m.routes, from)
+		}
+	}
+	route, ok := m.Route(p)
+	if !ok {
+		return
+	}
+	// only the routes that are longer than the way through the cleared tile have to be found again
+	steps := m.steps(route)
+	m.flood(p)
+	for from, r := range m.routes {
+		if i := m.idx(from); m.stamps[i] == m.stamp && m.scores[i]+steps < m.steps(r) {
+			delete(m.routes, from)
+		}
+	}
+}
+
+// Affected reports whether a route would have to change after the maze changed from version and cleared
+func (m *Maze) Affected(route []core.Point, version, cleared int) bool {
+	if cleared != m.cleared {
+		return true
+	}
+	if version == m.version {
+		return false
+	}
+	for _, p := range route {
+		if m.Blocked(p) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package graph
+
+import (
+	"tdgame/core"
+	"testing"
+)
+
+// walker is an enemy standing on a tile of a maze
+type walker struct {
+	l      core.Location
+	flying bool
+}
+
+func (w *walker) Location() core.Location { return w.l }
+func (w *walker) Center() core.Point      { return w.l.Center(core.DefaultTileSize) }
+func (w *walker) Radius() int             { return 1 }
+func (w *walker) Near(Collider) bool      { return false }
+func (w *walker) Kind() core.Kind         { return "walker" }
+func (w *walker) TakeDamage(int)          {}
+func (w *walker) Flying() bool            { return w.flying }
+
+// corridor is a maze 6 tiles wide and 3 high with its spawn on the west of the middle row and its exit on the east
+func corridor() *Maze {
+	return NewMaze(NewGraph(6, 3), []core.Point{core.Pt(0, 1)}, []core.Point{core.Pt(5, 1)})
+}
+
+func TestCanBlockSpawn(t *testing.T) {
+	m := corridor()
+	for _, p := range []core.Point{core.Pt(3, 0), core.Pt(3, 2)} {
+		if !m.Block(p) {
+			t.Fatalf("could not block %s", p)
+		}
+	}
+	if m.CanBlock(core.Pt(3, 1)) {
+		t.Error("blocking the last open tile of a column cuts the spawn off the exit")
+	}
+	if m.CanBlock(core.Pt(0, 1)) || m.CanBlock(core.Pt(5, 1)) {
+		t.Error("spawns and exits cannot be blocked")
+	}
+	if !m.CanBlock(core.Pt(1, 1)) {
+		t.Error("the spawn gets around a tile in front of it")
+	}
+}
+
+func TestCanBlockWalker(t *testing.T) {
+	m := corridor()
+	// a pocket at (2,0) that is only open to the south
+	for _, p := range []core.Point{core.Pt(1, 0), core.Pt(3, 0)} {
+		if !m.Block(p) {
+			t.Fatalf("could not block %s", p)
+		}
+	}
+	if !m.CanBlock(core.Pt(2, 1)) {
+		t.Fatal("an empty pocket can be closed off")
+	}
+	// cache a route from the pocket so only the partial search can find it cut off
+	if _, ok := m.Route(core.Pt(2, 0)); !ok {
+		t.Fatal("the pocket has a way out")
+	}
+	w := &walker{l: core.Loc(core.Pt(2, 0).Scale(core.DefaultTileSize), 0)}
+	m.Node(core.Pt(2, 0)).Add(w)
+	if m.CanBlock(core.Pt(2, 1)) {
+		t.Error("closing the pocket cuts the walker in it off the exit")
+	}
+	if m.CanBlock(core.Pt(2, 0)) {
+		t.Error("the tile of a walker cannot be blocked")
+	}
+	w.flying = true
+	if !m.CanBlock(core.Pt(2, 1)) {
+		t.Error("fliers do not need a way out")
+	}
+}
@@ -0,0 +1,242 @@
+package graph
+
+import (
+	"tdgame/asset"
+	"tdgame/core"
+)
+
+type (
+	// Branch leaves a tile of a path that is already on the map, it merges back into the path it reaches or
+	// leaves the map like a path. Weight is how likely enemies that pick randomly are to take it.
+	Branch struct {
+		From   core.Point
+		Weight int
+		Dirs   []core.Direction
+	}
+	// Segment is a run of tiles that enemies follow without making a choice, it starts as an enemy enters
+	// Start moving in Entry and ends before the next fork or once the enemy has left the map
+	Segment struct {
+		ID        int
+		Start     core.Point
+		Entry     core.Direction
+		Weight    int // weight of taking the segment at the fork it starts from
+		Remaining int // fewest tiles from the start of the segment to an exit
+		Tiles     []core.Point
+		Next      []*Segment // the choices at the end of the segment, none when it leaves the map
+		kinds     []core.Kind
+		tile      int // size in pixels of the tiles of the map, set once the map is cached
+	}
+	// exit is a way out of a tile, leaves is set for the last tile of a path
+	exit struct {
+		core.Direction
+		weight int
+		leaves bool
+	}
+	// router has the exits of every tile on a path by the direction enemies enter it moving in
+	router map[core.Point]map[core.Direction][]exit
+	// merge is a branch that ends on a tile of a path
+	merge struct {
+		core.Point
+		entry core.Direction
+	}
+	segmentKey struct {
+		core.Point
+		entry, exit core.Direction
+	}
+)
+
+const (
+	// ForkPrefix starts the line of a map file with the point a branch leaves from
+	ForkPrefix = "fork"
+)
+
+func (r router) add(p core.Point, entry core.Direction, ex exit) {
+	if r[p] == nil {
+		r[p] = make(map[core.Direction][]exit)
+	}
+	for i, o := range r[p][entry] {
+		if o.Direction == ex.Direction {
+			r[p][entry][i].leaves = o.leaves || ex.leaves
+			return
+		}
+	}
+	r[p][entry] = append(r[p][entry], ex)
+}
+
+func (r router) addPath(p *Path, dirs []core.Direction) {
+	tile, entry := p.Start, p.Entry
+	for _, d := range dirs {
+		r.add(tile, entry, exit{d, 1, false})
+		tile, entry = tile.Neighbor(d), d
+	}
+	r.add(tile, entry, exit{p.Exit, 1, true})
+}
+
+// addBranch routes a branch from every way into the tile it forks from, it reports the tile the branch
+// merges into if it ends on a path
+func (g BasicGraph) addBranch(r router, b Branch, aa asset.AssetAtlas) (merge, bool) {
+	if _, ok := r[b.From]; !ok || len(b.Dirs) == 0 {
+		panic("branch must fork from a tile of a path")
+	}
+	for entry := range r[b.From] {
+		r.add(b.From, entry, exit{b.Dirs[0], core.MaxInt(1, b.Weight), false})
+	}
+	p, entry := b.From.Neighbor(b.Dirs[0]), b.Dirs[0]
+	for _, d := range b.Dirs[1:] {
+		g.setTile(p, entry, d, aa)
+		r.add(p, entry, exit{d, 1, false})
+		p, entry = p.Neighbor(d), d
+	}
+	if _, ok := r[p]; ok {
+		return merge{p, entry}, true
+	}
+	out := borderDirection(entry, g.exits(p)...)
+	g.setTile(p, entry, out, aa)
+	r.add(p, entry, exit{out, 1, true})
+	return merge{}, false
+}
+
+// merge lets enemies coming from a branch take any way out of the tile they merge into besides turning back.
+// Entries are taken in the order of the directions so the segments of a map are numbered the same every time.
+func (r router) merge(m merge) {
+	exits := make([]exit, 0)
+	for _, entry := range core.Directions {
+		exits = append(exits, r[m.Point][entry]...)
+	}
+	for _, ex := range exits {
+		if ex.Direction != m.entry.Opposite() {
+			r.add(m.Point, m.entry, exit{ex.Direction, 1, ex.leaves})
+		}
+	}
+}
+
+// addJunctions changes the kind of every tile open on more than two sides to a T junction or crossroads
+func (g BasicGraph) addJunctions(r router, aa asset.AssetAtlas) {
+	for p, routes := range r {
+		open := make([]core.Direction, 0, 4)
+		add := func(d core.Direction) {
+			for _, o := range open {
+				if o == d {
+					return
+				}
+			}
+			open = append(open, d)
+		}
+		for entry, exs := range routes {
+			add(entry.Opposite())
+			for _, ex := range exs {
+				add(ex.Direction)
+			}
+		}
+		if len(open) > 2 {
+			nd := g.Node(p)
+			nd.k = core.JunctionKind(open)
+			nd.a = aa.Asset(nd.k)
+		}
+	}
+}
+
+// setDistances sets the distance to the closest exit of every routed tile
+func (g BasicGraph) setDistances(r router) {
+	dist := make(map[core.Point]int)
+	for changed := true; changed; {
+		changed = false
+		for p, routes := range r {
+			for _, exs := range routes {
+				for _, ex := range exs {
+					d := 1
+					if !ex.leaves {
+						next, ok := dist[p.Neighbor(ex.Direction)]
+						if !ok {
+							continue
+						}
+						d = next + 1
+					}
+					if cur, ok := dist[p]; !ok || d < cur {
+						dist[p], changed = d, true
+					}
+				}
+			}
+		}
+	}
+	for p, d := range dist {
+		g.Node(p).distanceToEnd = d
+	}
+}
+
+// segments splits the routes into the runs between forks reachable from the paths, limit is the most tiles a
+// segment can have before it is considered a loop without a way out
+func (r router) segments(paths []*Path, limit int) []*Segment {
+	segs, built := make([]*Segment, 0), make(map[segmentKey]*Segment)
+	var build func(p core.Point, entry core.Direction, ex exit) *Segment
+	build = func(p core.Point, entry core.Direction, ex exit) *Segment {
+		key := segmentKey{p, entry, ex.Direction}
+		if s, ok := built[key]; ok {
+			return s
+		}
+		s := &Segment{
+			ID:     len(segs),
+			Start:  p,
+			Entry:  entry,
+			Weight: ex.weight,
+			Tiles:  []core.Point{p},
+			kinds:  []core.Kind{core.DirectionsToKind(entry, ex.Direction)},
+		}
+		built[key], segs = s, append(segs, s)
+		for !ex.leaves {
+			p, entry = p.Neighbor(ex.Direction), ex.Direction
+			exs := r[p][entry]
+			if len(exs) == 0 {
+				panic("path leads to a tile that it cannot leave")
+			}
+			if len(exs) > 1 {
+				for _, n := range exs {
+					s.Next = append(s.Next, build(p, entry, n))
+				}
+				return s
+			}
+			if ex = exs[0]; len(s.Tiles) > limit {
+				panic("path loops without a fork")
+			}
+			s.Tiles = append(s.Tiles, p)
+			s.kinds = append(s.kinds, core.DirectionsToKind(entry, ex.Direction))
+		}
+		s.kinds = append(s.kinds, core.DirectionsToKind(ex.Direction, ex.Direction))
+		return s
+	}
+	for _, p := range paths {
+		// a fork on the spawn tile is only taken by enemies that merge into it
+		p.First = build(p.Start, p.Entry, r[p.Start][p.Entry][0])
+	}
+	for _, s := range segs {
+		s.Remaining = -1
+		if len(s.Next) == 0 {
+			s.Remaining = len(s.Tiles)
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, s := range segs {
+			for _, n := range s.Next {
+				if n.Remaining >= 0 && (s.Remaining < 0 || len(s.Tiles)+n.Remaining < s.Remaining) {
+					s.Remaining, changed = len(s.Tiles)+n.Remaining, true
+				}
+			}
+		}
+	}
+	return segs
+}
+
+func (s *Segment) Kinds() []core.Kind {
+	return s.kinds
+}
+
+// StartLoc is where an enemy is when it starts the segment, on the tile before Start facing into it
+func (s *Segment) StartLoc() core.Location {
+	return core.Loc(s.Start.Neighbor(s.Entry.Opposite()).Scale(s.tile), s.Entry.Rotation())
+}
+
+// Leaves reports whether the segment ends by leaving the map
+func (s *Segment) Leaves() bool {
+	return len(s.Next) == 0
+}
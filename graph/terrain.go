@@ -0,0 +1,167 @@
+package graph
+
+import (
+	"fmt"
+	"image/color"
+	"sort"
+	"tdgame/asset"
+	"tdgame/core"
+)
+
+type (
+	// Modifiers are what the terrain of a node does to the enemies walking through it and the towers built on it
+	Modifiers struct {
+		Speed     int  // percent of their speed enemies walk through the terrain at
+		Buildable bool // towers can be built on the terrain off the path
+		Range     int  // percent of extra range for towers on the terrain
+		Damage    int  // percent of extra damage for towers on the terrain
+	}
+	TerrainAttributes struct {
+		Asset     core.Kind // tile drawn for the blank nodes of the terrain, the terrain is tinted when it has none
+		Modifiers `yaml:",inline"`
+	}
+	TerrainSpec struct {
+		core.Meta
+		TerrainAttributes `yaml:"attributes"`
+	}
+	TerrainAtlas map[core.Kind]*TerrainSpec
+)
+
+const (
+	TerrainType  = "terrain"
+	BasicVariety = "basic"
+	// Terrains, mud, high ground and road can only be used once they are declared
+	Grass      core.Kind = "grass"
+	Water      core.Kind = "water"
+	Rock       core.Kind = "rock"
+	Mud        core.Kind = "mud"
+	HighGround core.Kind = "highground"
+	Road       core.Kind = "road"
+)
+
+var (
+	// DefaultTerrains are used for the terrains that are not declared
+	DefaultTerrains = TerrainAtlas{
+		Grass: {core.Meta{Type: TerrainType, Variety: BasicVariety, Name: Grass}, TerrainAttributes{"", Modifiers{100, true, 0, 0}}},
+		Water: {core.Meta{Type: TerrainType, Variety: BasicVariety, Name: Water}, TerrainAttributes{"", Modifiers{100, false, 0, 0}}},
+		Rock:  {core.Meta{Type: TerrainType, Variety: BasicVariety, Name: Rock}, TerrainAttributes{"", Modifiers{100, false, 0, 0}}},
+	}
+	// terrainTints are drawn over the blank tiles of terrains that have no asset of their own
+	terrainTints = map[core.Kind]color.Color{
+		Water:      color.RGBA{40, 90, 200, 160},
+		Rock:       color.RGBA{90, 90, 90, 200},
+		Mud:        color.RGBA{80, 50, 20, 160},
+		HighGround: color.RGBA{230, 220, 160, 120},
+		Road:       color.RGBA{150, 140, 130, 160},
+	}
+	// NoModifiers are the modifiers off the map
+	NoModifiers = Modifiers{100, false, 0, 0}
+)
+
+var _ core.DeclarationHandler = TerrainAtlas{}
+
+func NewTerrainAtlas() TerrainAtlas {
+	return make(TerrainAtlas)
+}
+
+func (ta TerrainAtlas) Type() core.Kind {
+	return TerrainType
+}
+
+// Match starts every terrain at full speed so that declarations only list what the terrain changes
+func (ta TerrainAtlas) Match(pm *core.PreMeta) (spec core.Kinder, priority int) {
+	switch pm.Variety {
+	case BasicVariety:
+		return &TerrainSpec{TerrainAttributes: TerrainAttributes{Modifiers: Modifiers{Speed: 100}}}, 1
+	default:
+		panic("variety of terrain does not exist")
+	}
+}
+
+func (ta TerrainAtlas) PreLoad(d *core.Declarations) {
+
+}
+
+func (ta TerrainAtlas) Load(spec core.Kinder, decs *core.Declarations) {
+	switch ts := spec.(type) {
+	case *TerrainSpec:
+		if ts.Speed <= 0 {
+			panic(fmt.Sprintf("terrain %s: speed must be above 0", ts.Name))
+		}
+		if aa := decs.Get(asset.AssetType).(asset.AssetAtlas); ts.Asset != "" && aa[ts.Asset] == nil {
+			panic(fmt.Sprintf("terrain %s: asset %s does not exist", ts.Name, ts.Asset))
+		}
+		ta[ts.Name] = ts
+	default:
+		panic("variety of terrain does not exist")
+	}
+}
+
+// Terrain is the declared terrain of a kind or the default one
+func (ta TerrainAtlas) Terrain(k core.Kind) (*TerrainSpec, bool) {
+	if ts, ok := ta[k]; ok {
+		return ts, true
+	}
+	ts, ok := DefaultTerrains[k]
+	return ts, ok
+}
+
+// Terrain is the terrain of a kind that maps of the spec can use
+func (spec *GraphSpec) Terrain(k core.Kind) (*TerrainSpec, bool) {
+	return spec.terrains.Terrain(k)
+}
+
+// Terrains are the kinds of every terrain maps of the spec can use, sorted by name
+func (spec *GraphSpec) Terrains() []core.Kind {
+	ret := make([]core.Kind, 0, len(spec.terrains)+len(DefaultTerrains))
+	for k := range DefaultTerrains {
+		ret = append(ret, k)
+	}
+	for k := range spec.terrains {
+		if _, ok := DefaultTerrains[k]; !ok {
+			ret = append(ret, k)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i] < ret[j] })
+	return ret
+}
+
+// applyTerrain gives every node the modifiers of its terrain and blank nodes the tile of their terrain
+func (spec *GraphSpec) applyTerrain(g BasicGraph, aa asset.AssetAtlas) {
+	blank := aa.Blank()
+	for _, row := range g {
+		for _, n := range row {
+			ts, ok := spec.Terrain(n.terrain)
+			if !ok {
+				panic(fmt.Sprintf("map %s: terrain %s does not exist", spec.Name, n.terrain))
+			}
+			n.mods, n.tint = ts.Modifiers, nil
+			if !n.IsBlank() {
+				continue
+			}
+			if ts.Asset != "" {
+				n.a = aa[ts.Asset]
+				continue
+			}
+			n.a, n.tint = blank, terrainTints[n.terrain]
+		}
+	}
+}
+
+// cost is the cost of walking through the terrain relative to DefaultCost
+func (m Modifiers) cost() int {
+	return DefaultCost * 100 / m.Speed
+}
+
+// Modifiers are the modifiers of the terrain of the node
+func (n *Node) Modifiers() Modifiers {
+	return n.mods
+}
+
+// ModifiersAt are the modifiers of the tile under a pixel
+func (g CachedImageGraph) ModifiersAt(p core.Point) Modifiers {
+	if nd := g.Node(p.TileIndex(g.TileSize)); nd != nil {
+		return nd.mods
+	}
+	return NoModifiers
+}
@@ -0,0 +1,542 @@
+package graph
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"io"
+	"io/ioutil"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"tdgame/asset"
+	"tdgame/core"
+
+	"github.com/fogleman/gg"
+)
+
+type (
+	// tiledProperty is a custom property of Tiled, JSON keeps the value as it was typed and TMX keeps it as text
+	tiledProperty struct {
+		Name  string      `json:"name" xml:"name,attr"`
+		Value interface{} `json:"value" xml:"-"`
+		Text  string      `json:"-" xml:"value,attr"`
+	}
+	tiledProperties []tiledProperty
+	tiledTile       struct {
+		ID         int             `json:"id" xml:"id,attr"`
+		Properties tiledProperties `json:"properties" xml:"properties>property"`
+	}
+	// tiledTileset is cut into sub-images like a multi asset, a tileset with a source is read from that file
+	tiledTileset struct {
+		FirstGID uint32 `json:"firstgid" xml:"firstgid,attr"`
+		Source   string `json:"source" xml:"source,attr"`
+		Name     string `json:"name" xml:"name,attr"`
+		Image    string `json:"image" xml:"-"`
+		TMXImage struct {
+			Source string `xml:"source,attr"`
+		} `json:"-" xml:"image"`
+		TileWidth  int         `json:"tilewidth" xml:"tilewidth,attr"`
+		TileHeight int         `json:"tileheight" xml:"tileheight,attr"`
+		TileCount  int         `json:"tilecount" xml:"tilecount,attr"`
+		Columns    int         `json:"columns" xml:"columns,attr"`
+		Margin     int         `json:"margin" xml:"margin,attr"`
+		Spacing    int         `json:"spacing" xml:"spacing,attr"`
+		Tiles      []tiledTile `json:"tiles" xml:"tile"`
+		images     []image.Image
+		props      map[int]tiledProperties
+	}
+	tiledObject struct {
+		ID         int             `json:"id" xml:"id,attr"`
+		Name       string          `json:"name" xml:"name,attr"`
+		Type       string          `json:"type" xml:"type,attr"`
+		Class      string          `json:"class" xml:"class,attr"`
+		X          float64         `json:"x" xml:"x,attr"`
+		Y          float64         `json:"y" xml:"y,attr"`
+		Width      float64         `json:"width" xml:"width,attr"`
+		Height     float64         `json:"height" xml:"height,attr"`
+		Rotation   float64         `json:"rotation" xml:"rotation,attr"`
+		GID        uint32          `json:"gid" xml:"gid,attr"`
+		Properties tiledProperties `json:"properties" xml:"properties>property"`
+		Polyline   []struct {
+			X float64 `json:"x"`
+			Y float64 `json:"y"`
+		} `json:"polyline" xml:"-"`
+		TMXPolyline struct {
+			Points string `xml:"points,attr"`
+		} `json:"-" xml:"polyline"`
+	}
+	// tiledLayer is any layer of a map, TMX tells layers apart by their element and JSON by their type
+	tiledLayer struct {
+		XMLName     xml.Name        `json:"-"`
+		Type        string          `json:"type" xml:"-"`
+		Name        string          `json:"name" xml:"name,attr"`
+		Width       int             `json:"width" xml:"width,attr"`
+		Height      int             `json:"height" xml:"height,attr"`
+		OffsetX     float64         `json:"offsetx" xml:"offsetx,attr"`
+		OffsetY     float64         `json:"offsety" xml:"offsety,attr"`
+		Visible     *bool           `json:"visible" xml:"visible,attr"`
+		Properties  tiledProperties `json:"properties" xml:"properties>property"`
+		Data        json.RawMessage `json:"data" xml:"-"`
+		Encoding    string          `json:"encoding" xml:"-"`
+		Compression string          `json:"compression" xml:"-"`
+		TMXData     struct {
+			Encoding    string `xml:"encoding,attr"`
+			Compression string `xml:"compression,attr"`
+			Text        string `xml:",chardata"`
+			Tiles       []struct {
+				GID uint32 `xml:"gid,attr"`
+			} `xml:"tile"`
+		} `json:"-" xml:"data"`
+		Objects []tiledObject `json:"objects" xml:"object"`
+		Layers  []tiledLayer  `json:"layers" xml:",any"`
+	}
+	tiledMap struct {
+		Orientation string          `json:"orientation" xml:"orientation,attr"`
+		Infinite    bool            `json:"infinite" xml:"infinite,attr"`
+		Width       int             `json:"width" xml:"width,attr"`
+		Height      int             `json:"height" xml:"height,attr"`
+		TileWidth   int             `json:"tilewidth" xml:"tilewidth,attr"`
+		TileHeight  int             `json:"tileheight" xml:"tileheight,attr"`
+		Properties  tiledProperties `json:"properties" xml:"properties>property"`
+		Tilesets    []*tiledTileset `json:"tilesets" xml:"tileset"`
+		Layers      []tiledLayer    `json:"layers" xml:",any"`
+		file        string
+		spec        *GraphSpec
+	}
+	// tiledArt is an image of a tileset drawn over the map with its top left corner at a pixel
+	tiledArt struct {
+		image.Image
+		at core.Point
+	}
+)
+
+const (
+	TiledVariety = "tiled"
+	// Types of the objects of a Tiled map, spawns, exits and towers are the same as in grid maps
+	WaypointObject   = "waypoint"
+	DecorationObject = "decoration"
+	// Properties of the tiles of a Tiled tileset
+	TerrainProperty = "terrain"
+	PathProperty    = "path"
+	// KindProperty is the kind of tower a tower object places
+	KindProperty = "kind"
+	// tiledFlipped are the bits of a tile id that flip or rotate the tile
+	tiledFlipped = 0xF0000000
+)
+
+var _ core.Drawer = tiledArt{}
+
+// TiledFromSpec reads a map made in the Tiled editor from its TMX or JSON file. Tile layers give the terrain of
+// every tile through the terrain property of their tiles and lay the road through tiles whose path property is
+// true, hidden layers count but are not drawn. Object layers hold spawn, exit and waypoint objects that lay path
+// tiles under them, tower objects with the kind of tower they place and decorations drawn over the map. The
+// paths are found like those of grid maps and the map is drawn with the art of its tilesets over the tiles.
+func TiledFromSpec(spec *GraphSpec, aa asset.AssetAtlas) CachedImageGraph {
+	file := path.Join(spec.FilePath, spec.File)
+	tm := &tiledMap{file: spec.File, spec: spec}
+	decodeTiled(file, tm)
+	tm.check()
+	for _, ts := range tm.Tilesets {
+		ts.load(path.Dir(file))
+	}
+	gm := &gridMap{file: spec.File, tiles: make([][]GridTile, tm.Height)}
+	for y := range gm.tiles {
+		gm.tiles[y] = make([]GridTile, tm.Width)
+		for x := range gm.tiles[y] {
+			gm.tiles[y][x] = GridTile{Grass, ""}
+		}
+	}
+	art := make([]core.Drawer, 0)
+	for _, l := range tm.flatten(tm.Layers, nil) {
+		if l.kind() == "tilelayer" {
+			art = tm.addTiles(gm, l, art)
+		} else {
+			art = tm.addObjects(gm, l, art)
+		}
+	}
+	for y, row := range gm.tiles {
+		for x, t := range row {
+			switch t.Meaning {
+			case SpawnTile:
+				gm.spawns = append(gm.spawns, core.Pt(x, y))
+			case ExitTile:
+				gm.exits = append(gm.exits, core.Pt(x, y))
+			}
+		}
+	}
+	if len(gm.spawns) == 0 || len(gm.exits) == 0 {
+		panic(fmt.Sprintf("map %s: map must have at least 1 spawn and 1 exit object", spec.File))
+	}
+	ret := gm.graph(spec, aa)
+	ret.imageWithGrid, ret.image = render(ret.BasicGraph, spec.TileSize, art...)
+	return ret
+}
+
+// decodeTiled reads a Tiled map or tileset into v, TMX and TSX files are XML and the others JSON
+func decodeTiled(file string, v interface{}) {
+	data, err := ioutil.ReadFile(file)
+	core.Check(err)
+	switch path.Ext(file) {
+	case ".tmx", ".tsx":
+		err = xml.Unmarshal(data, v)
+	case ".json", ".tmj", ".tsj":
+		err = json.Unmarshal(data, v)
+	default:
+		panic(fmt.Sprintf("map %s: only Tiled .tmx, .tsx, .tmj, .tsj and .json files can be read", file))
+	}
+	if err != nil {
+		panic(fmt.Sprintf("map %s: %s", file, err))
+	}
+}
+
+// check rejects maps the game cannot play the way Tiled shows them
+func (tm *tiledMap) check() {
+	if tm.Infinite {
+		panic(fmt.Sprintf("map %s: infinite maps are not supported, the map must have a fixed size", tm.file))
+	}
+	if tm.Orientation != "orthogonal" {
+		panic(fmt.Sprintf("map %s: %s maps are not supported, the map must be orthogonal", tm.file, tm.Orientation))
+	}
+	if tile := tm.spec.TileSize; tm.TileWidth != tile || tm.TileHeight != tile {
+		panic(fmt.Sprintf("map %s: tiles are %dx%d pixels but the declaration's tile size is %d", tm.file, tm.TileWidth, tm.TileHeight, tile))
+	}
+	if tm.Width <= 0 || tm.Height <= 0 {
+		panic(fmt.Sprintf("map %s: map has no tiles", tm.file))
+	}
+}
+
+// flatten lists the tile and object layers of the map in the order they are drawn, the layers of groups take
+// the place of the group
+func (tm *tiledMap) flatten(layers []tiledLayer, ret []*tiledLayer) []*tiledLayer {
+	for i := range layers {
+		l := &layers[i]
+		switch l.kind() {
+		case "tilelayer", "objectgroup":
+			if l.OffsetX != 0 || l.OffsetY != 0 {
+				panic(fmt.Sprintf("map %s: layer %s has an offset, layers with offsets are not supported", tm.file, l.Name))
+			}
+			ret = append(ret, l)
+		case "group":
+			ret = tm.flatten(l.Layers, ret)
+		case "imagelayer":
+			panic(fmt.Sprintf("map %s: layer %s is an image layer, image layers are not supported", tm.file, l.Name))
+		}
+	}
+	return ret
+}
+
+// kind is the JSON type of the layer, elements of TMX files that are not layers have none
+func (l *tiledLayer) kind() string {
+	switch l.XMLName.Local {
+	case "":
+		return l.Type
+	case "layer":
+		return "tilelayer"
+	case "objectgroup", "group", "imagelayer":
+		return l.XMLName.Local
+	}
+	return ""
+}
+
+func (l *tiledLayer) visible() bool {
+	return l.Visible == nil || *l.Visible
+}
+
+// addTiles applies the properties of the tiles of a tile layer to the map and adds their art when the layer
+// is visible
+func (tm *tiledMap) addTiles(gm *gridMap, l *tiledLayer, art []core.Drawer) []core.Drawer {
+	gids := tm.gids(l)
+	for i, gid := range gids {
+		if gid == 0 {
+			continue
+		}
+		x, y := i%tm.Width, i/tm.Width
+		if gid&tiledFlipped != 0 {
+			gm.fail(y, x, fmt.Sprintf("tile of layer %s is flipped or rotated, which is not supported", l.Name))
+		}
+		ts, id := tm.tileset(gid)
+		if ts == nil {
+			gm.fail(y, x, fmt.Sprintf("tile %d of layer %s is in no tileset", gid, l.Name))
+		}
+		props := ts.props[id]
+		if t, ok := props.get(TerrainProperty); ok {
+			if _, ok := tm.spec.Terrain(core.Kind(t)); !ok {
+				panic(fmt.Sprintf("map %s: tile %d of tileset %s has terrain %s which does not exist", tm.file, id, ts.Name, t))
+			}
+			if gm.isPath(core.Pt(x, y)) {
+				gm.tiles[y][x].Arg = core.Kind(t)
+			} else {
+				gm.tiles[y][x] = GridTile{core.Kind(t), ""}
+			}
+		}
+		if p, ok := props.get(PathProperty); ok {
+			isPath, err := strconv.ParseBool(p)
+			if err != nil {
+				panic(fmt.Sprintf("map %s: tile %d of tileset %s has path %q which must be true or false", tm.file, id, ts.Name, p))
+			}
+			if isPath && !gm.isPath(core.Pt(x, y)) {
+				gm.tiles[y][x] = GridTile{PathTile, gm.terrain(core.Pt(x, y))}
+			}
+		}
+		if l.visible() {
+			img := ts.images[id]
+			// tiles taller than the map's tiles stick out of the top of their tile like they do in Tiled
+			at := core.Pt(x*tm.spec.TileSize, (y+1)*tm.spec.TileSize-img.Bounds().Dy())
+			art = append(art, tiledArt{img, at})
+		}
+	}
+	return art
+}
+
+// addObjects applies the objects of an object layer to the map and adds the art of its decorations when the
+// layer is visible
+func (tm *tiledMap) addObjects(gm *gridMap, l *tiledLayer, art []core.Drawer) []core.Drawer {
+	for _, o := range l.Objects {
+		switch core.Kind(o.kind()) {
+		case SpawnTile, ExitTile:
+			p := tm.objectTile(gm, &o, o.anchor())
+			gm.tiles[p.Y()][p.X()] = GridTile{core.Kind(o.kind()), gm.terrain(p)}
+		case WaypointObject:
+			tm.lay(gm, &o)
+		case TowerTile:
+			k, ok := o.Properties.get(KindProperty)
+			if !ok || k == "" {
+				panic(fmt.Sprintf("map %s: tower object %d must have a %s property with the kind of tower", tm.file, o.ID, KindProperty))
+			}
+			p := tm.objectTile(gm, &o, o.anchor())
+			if gm.isPath(p) {
+				gm.fail(p.Y(), p.X(), fmt.Sprintf("tower object %d is on the path", o.ID))
+			}
+			gm.tiles[p.Y()][p.X()] = GridTile{TowerTile, core.Kind(k)}
+		case DecorationObject:
+			if o.GID == 0 {
+				panic(fmt.Sprintf("map %s: decoration object %d must be a tile object", tm.file, o.ID))
+			}
+			if o.GID&tiledFlipped != 0 || o.Rotation != 0 {
+				panic(fmt.Sprintf("map %s: decoration object %d is flipped or rotated, which is not supported", tm.file, o.ID))
+			}
+			ts, id := tm.tileset(o.GID)
+			if ts == nil {
+				panic(fmt.Sprintf("map %s: decoration object %d has tile %d which is in no tileset", tm.file, o.ID, o.GID))
+			}
+			if !l.visible() {
+				continue
+			}
+			// tile objects are anchored at their bottom left corner and drawn at the size of their tile
+			img := ts.images[id]
+			art = append(art, tiledArt{img, core.Pt(int(o.X), int(o.Y)-img.Bounds().Dy())})
+		default:
+			panic(fmt.Sprintf("map %s: object %d has type %q, objects must be a %s, %s, %s, %s or %s", tm.file, o.ID, o.kind(),
+				SpawnTile, ExitTile, WaypointObject, TowerTile, DecorationObject))
+		}
+	}
+	return art
+}
+
+// lay turns every tile along a waypoint into a path tile, the lines of a polyline must be straight across a
+// row or a column of tiles
+func (tm *tiledMap) lay(gm *gridMap, o *tiledObject) {
+	pts := o.points()
+	if len(pts) == 0 {
+		pts = [][2]float64{o.anchor()}
+	}
+	prev := tm.objectTile(gm, o, pts[0])
+	for _, pt := range pts {
+		p := tm.objectTile(gm, o, pt)
+		if p.X() != prev.X() && p.Y() != prev.Y() {
+			panic(fmt.Sprintf("map %s: waypoint object %d goes from %s to %s, lines of waypoints must follow a row or a column", tm.file, o.ID, prev, p))
+		}
+		for t := prev; ; t = t.Neighbor(towards(t, p)) {
+			if !gm.isPath(t) {
+				gm.tiles[t.Y()][t.X()] = GridTile{PathTile, gm.terrain(t)}
+			}
+			if t == p {
+				break
+			}
+		}
+		prev = p
+	}
+}
+
+// towards is the direction from a tile to another in the same row or column
+func towards(from, to core.Point) core.Direction {
+	switch {
+	case to.X() > from.X():
+		return core.E
+	case to.X() < from.X():
+		return core.W
+	case to.Y() > from.Y():
+		return core.S
+	}
+	return core.N
+}
+
+// objectTile is the tile under a pixel of an object
+func (tm *tiledMap) objectTile(gm *gridMap, o *tiledObject, pt [2]float64) core.Point {
+	tile := float64(tm.spec.TileSize)
+	p := core.Pt(int(math.Floor(pt[0]/tile)), int(math.Floor(pt[1]/tile)))
+	if !gm.contains(p) {
+		panic(fmt.Sprintf("map %s: %s object %d is off the map", tm.file, o.kind(), o.ID))
+	}
+	return p
+}
+
+// gids are the tile ids of a tile layer, row by row
+func (tm *tiledMap) gids(l *tiledLayer) []uint32 {
+	var ret []uint32
+	switch {
+	case len(l.Data) > 0 && l.Data[0] == '[':
+		core.Check(json.Unmarshal(l.Data, &ret))
+	case len(l.Data) > 0:
+		var s string
+		core.Check(json.Unmarshal(l.Data, &s))
+		ret = tm.decode(l, l.Encoding, l.Compression, s)
+	case l.TMXData.Encoding == "":
+		for _, t := range l.TMXData.Tiles {
+			ret = append(ret, t.GID)
+		}
+	case l.TMXData.Encoding == "csv":
+		for _, f := range strings.Split(l.TMXData.Text, ",") {
+			gid, err := strconv.ParseUint(strings.TrimSpace(f), 10, 32)
+			if err != nil {
+				panic(fmt.Sprintf("map %s: layer %s has tile %q which is not a number", tm.file, l.Name, f))
+			}
+			ret = append(ret, uint32(gid))
+		}
+	default:
+		ret = tm.decode(l, l.TMXData.Encoding, l.TMXData.Compression, l.TMXData.Text)
+	}
+	if len(ret) != tm.Width*tm.Height {
+		panic(fmt.Sprintf("map %s: layer %s has %d tiles but the map has %d", tm.file, l.Name, len(ret), tm.Width*tm.Height))
+	}
+	return ret
+}
+
+// decode reads tile ids stored as base64, zstd compression is not supported
+func (tm *tiledMap) decode(l *tiledLayer, encoding, compression, data string) []uint32 {
+	if encoding != "base64" {
+		panic(fmt.Sprintf("map %s: layer %s has encoding %s, tile layers must be csv or base64", tm.file, l.Name, encoding))
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(data))
+	core.Check(err)
+	var r io.Reader = bytes.NewReader(raw)
+	switch compression {
+	case "":
+	case "zlib":
+		r, err = zlib.NewReader(r)
+	case "gzip":
+		r, err = gzip.NewReader(r)
+	default:
+		panic(fmt.Sprintf("map %s: layer %s has compression %s, tile layers must be uncompressed, zlib or gzip", tm.file, l.Name, compression))
+	}
+	core.Check(err)
+	raw, err = ioutil.ReadAll(r)
+	core.Check(err)
+	ret := make([]uint32, len(raw)/4)
+	for i := range ret {
+		ret[i] = binary.LittleEndian.Uint32(raw[4*i:])
+	}
+	return ret
+}
+
+// tileset is the tileset a tile id belongs to and the index of the tile in it
+func (tm *tiledMap) tileset(gid uint32) (*tiledTileset, int) {
+	var ret *tiledTileset
+	for _, ts := range tm.Tilesets {
+		if ts.FirstGID <= gid && (ret == nil || ts.FirstGID > ret.FirstGID) {
+			ret = ts
+		}
+	}
+	if ret == nil || int(gid-ret.FirstGID) >= len(ret.images) {
+		return nil, 0
+	}
+	return ret, int(gid - ret.FirstGID)
+}
+
+// load reads an external tileset and cuts its image into one sub-image per tile
+func (ts *tiledTileset) load(dir string) {
+	if ts.Source != "" {
+		first, file := ts.FirstGID, path.Join(dir, ts.Source)
+		decodeTiled(file, ts)
+		ts.FirstGID, dir = first, path.Dir(file)
+	}
+	file := ts.Image
+	if file == "" {
+		file = ts.TMXImage.Source
+	}
+	if file == "" {
+		panic(fmt.Sprintf("tileset %s: collections of images are not supported, the tileset must be a single image", ts.Name))
+	}
+	sheet := asset.ReadPNG(path.Join(dir, file)).(asset.SubImager)
+	columns := ts.Columns
+	if columns == 0 {
+		columns = 1
+	}
+	ts.images = make([]image.Image, ts.TileCount)
+	for i := range ts.images {
+		x := ts.Margin + i%columns*(ts.TileWidth+ts.Spacing)
+		y := ts.Margin + i/columns*(ts.TileHeight+ts.Spacing)
+		ts.images[i] = sheet.SubImage(image.Rect(x, y, x+ts.TileWidth, y+ts.TileHeight))
+	}
+	ts.props = make(map[int]tiledProperties)
+	for _, t := range ts.Tiles {
+		ts.props[t.ID] = t.Properties
+	}
+}
+
+func (ps tiledProperties) get(name string) (string, bool) {
+	for _, p := range ps {
+		if p.Name == name {
+			if p.Value != nil {
+				return fmt.Sprint(p.Value), true
+			}
+			return p.Text, true
+		}
+	}
+	return "", false
+}
+
+// kind is the type of the object, Tiled 1.9 calls it the class
+func (o *tiledObject) kind() string {
+	if o.Type != "" {
+		return o.Type
+	}
+	return o.Class
+}
+
+// anchor is the pixel of the object that picks its tile, the center of shapes and of tile objects
+func (o *tiledObject) anchor() [2]float64 {
+	if o.GID != 0 {
+		return [2]float64{o.X + o.Width/2, o.Y - o.Height/2}
+	}
+	return [2]float64{o.X + o.Width/2, o.Y + o.Height/2}
+}
+
+// points are the pixels of the corners of a polyline
+func (o *tiledObject) points() [][2]float64 {
+	ret := make([][2]float64, 0, len(o.Polyline))
+	for _, p := range o.Polyline {
+		ret = append(ret, [2]float64{o.X + p.X, o.Y + p.Y})
+	}
+	for _, f := range strings.Fields(o.TMXPolyline.Points) {
+		xy := strings.Split(f, ",")
+		x, err := strconv.ParseFloat(xy[0], 64)
+		core.Check(err)
+		y, err := strconv.ParseFloat(xy[len(xy)-1], 64)
+		core.Check(err)
+		ret = append(ret, [2]float64{o.X + x, o.Y + y})
+	}
+	return ret
+}
+
+func (a tiledArt) Draw(con *gg.Context) {
+	con.DrawImage(a.Image, a.at.X()-a.Bounds().Min.X, a.at.Y()-a.Bounds().Min.Y)
+}
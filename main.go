@@ -1,7 +1,9 @@
 package main
 
 import (
+	"flag"
 	"tdgame/core"
+	"tdgame/editor"
 	"tdgame/game"
 
 	"github.com/hajimehoshi/ebiten/v2"
@@ -11,6 +13,8 @@ const (
 	// MaxTPS = 32 * 512
 	MaxTPS = 32
 	Title  = "Tower Defense"
+	// Declarations is the directory the game is declared in
+	Declarations = "./0_gamedata/declarations"
 )
 
 func configureEbiten() {
@@ -19,8 +23,18 @@ func configureEbiten() {
 }
 
 func main() {
+	edit := flag.String("edit", "", "grid map file to edit instead of playing, a new map is made when it does not exist")
+	width := flag.Int("width", editor.DefaultWidth, "width in tiles of a new map")
+	height := flag.Int("height", editor.DefaultHeight, "height in tiles of a new map")
+	flag.Parse()
 	configureEbiten()
-	g := game.NewGame("./0_gamedata/declarations")
+	if *edit != "" {
+		e := game.NewEditor(Declarations, *edit, *width, *height)
+		ebiten.SetWindowSize(e.Layout(0, 0))
+		core.Check(ebiten.RunGame(e))
+		return
+	}
+	g := game.NewGame(Declarations)
 	// f, err := os.Create("poolprofile")
 	// util.Check(err)
 	// pprof.StartCPUProfile(f)
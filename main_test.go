@@ -1 +1,70 @@
 package main
+
+import (
+	"tdgame/animator"
+	"tdgame/asset"
+	"tdgame/core"
+	"tdgame/graph"
+	"tdgame/td"
+	"testing"
+)
+
+func loadDeclarations(b *testing.B) *core.Declarations {
+	b.Helper()
+	decs := core.NewDeclarations()
+	decs.RegisterHandlers(
+		asset.NewAssetAtlas(),
+		graph.NewTerrainAtlas(),
+		graph.NewGraphAtlas(),
+		animator.DefaultAnimatorAtlas,
+		td.NewTowerAtlas(),
+		td.NewEnemyAtlas(),
+	).AddDir(
+		"./0_gamedata/declarations",
+	).Load()
+	return decs
+}
+
+// BenchmarkEnemyCopy creates a new enemy for every spawn the way rounds used to
+func BenchmarkEnemyCopy(b *testing.B) {
+	ea := loadDeclarations(b).Get(td.EnemyType).(*td.EnemyAtlas)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ea.Enemy(core.ZeroLoc, "slug")
+	}
+}
+
+// BenchmarkEnemyPool takes every spawn from the enemy pool and releases it when it is done
+func BenchmarkEnemyPool(b *testing.B) {
+	ea := loadDeclarations(b).Get(td.EnemyType).(*td.EnemyAtlas)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		e := ea.Spawn(core.ZeroLoc, "slug")
+		e.(td.Particle).Release()
+	}
+}
+
+// BenchmarkTick runs a round against every tower, allocations per tick should level off once the pools
+// have grown to their high water marks
+func BenchmarkTick(b *testing.B) {
+	decs := loadDeclarations(b)
+	g := decs.Get(graph.GraphType).(graph.GraphAtlas).Graph("map").(graph.CachedImageGraph)
+	ea := decs.Get(td.EnemyType).(*td.EnemyAtlas)
+	ta := decs.Get(td.TowerType).(*td.TowerAtlas)
+	l := core.NewLayers(int(core.NumberOfLayers))
+	for i, k := range []core.Kind{"cannon", "mortar", "seeker", "laser"} {
+		l.Add(core.TowerLayer, ta.Tower(core.Loc(core.Pt(128*(i%2+1), 128*(i/2+1)), 0), k))
+	}
+	attrs := core.NewAttributes()
+	td.ShowDamageText = false
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if i%20 == 0 {
+			l.Add(core.EnemyLayer, ea.Spawn(g.Path(0).StartLoc(), "slug"))
+		}
+		l.Process(i, attrs)
+	}
+}
@@ -0,0 +1,162 @@
+package td
+
+import (
+	"fmt"
+	"sort"
+	"tdgame/core"
+)
+
+type (
+	// AbilityAttributes declare something an enemy does when its health drops to a threshold
+	AbilityAttributes struct {
+		Kind      core.Kind
+		Threshold int         // percent of health at or below which a single ability is used
+		Speed     int         // percent of normal speed the enemy moves at from now on
+		Children  []core.Kind // enemies summoned where the enemy is
+		Spread    int         // path ticks between each summoned enemy
+		Duration  int         // ticks that invulnerability or disabled towers last
+		Radius    int         // pixels from the center of the enemy that towers are disabled in
+	}
+	// PhaseAttributes are the abilities used together once health drops to a threshold
+	PhaseAttributes struct {
+		Threshold int // percent of health
+		Abilities []AbilityAttributes
+	}
+	// Abilities uses each phase of an enemy once as its health drops
+	Abilities struct {
+		phases       []PhaseAttributes
+		atlas        *EnemyAtlas
+		next, speed  int
+		invulnerable int // ticks left
+	}
+	// Disruption disables towers within a radius of a point until a tick
+	Disruption struct {
+		core.Point
+		Radius, Until int
+	}
+	Disruptions struct {
+		active []Disruption
+	}
+)
+
+const (
+	SpeedAbility        core.Kind = "speed"
+	SummonAbility       core.Kind = "summon"
+	InvulnerableAbility core.Kind = "invulnerable"
+	DisableAbility      core.Kind = "disable"
+	// DisruptionsKey is the context attribute of the areas where towers are disabled
+	DisruptionsKey core.ContextKey = "disruptions"
+)
+
+// NewAbilities sorts the phases from the highest threshold to the lowest, a single ability is its own phase
+func NewAbilities(phases []PhaseAttributes, single AbilityAttributes, atlas *EnemyAtlas) *Abilities {
+	ps := append([]PhaseAttributes{}, phases...)
+	if single.Kind != "" {
+		ps = append(ps, PhaseAttributes{single.Threshold, []AbilityAttributes{single}})
+	}
+	sort.SliceStable(ps, func(i, j int) bool {
+		return ps[i].Threshold > ps[j].Threshold
+	})
+	return &Abilities{ps, atlas, 0, 100, 0}
+}
+
+// Process uses the abilities of every phase whose threshold the health of e has crossed
+func (a *Abilities) Process(ticks int, con core.Context, e *BasicEnemy) {
+	if a.invulnerable > 0 {
+		a.invulnerable--
+	}
+	for a.next < len(a.phases) && !e.Destroyed() && e.health*100 <= a.phases[a.next].Threshold*e.max {
+		for i := range a.phases[a.next].Abilities {
+			a.Use(&a.phases[a.next].Abilities[i], ticks, con, e)
+		}
+		a.next++
+	}
+}
+
+func (a *Abilities) Use(ab *AbilityAttributes, ticks int, con core.Context, e *BasicEnemy) {
+	switch ab.Kind {
+	case SpeedAbility:
+		a.speed = ab.Speed
+	case SummonAbility:
+		spawnChildren(con, a.atlas, e.self, ab.Children, ab.Spread)
+	case InvulnerableAbility:
+		a.invulnerable = core.MaxInt(a.invulnerable, ab.Duration)
+	case DisableAbility:
+		Disrupt(con, Disruption{e.Center(), ab.Radius, ticks + ab.Duration})
+	default:
+		panic("kind of ability does not exist")
+	}
+}
+
+// Phase is the number of phases that have been triggered
+func (a *Abilities) Phase() int {
+	return a.next
+}
+
+func (a *Abilities) SpeedPercent() int {
+	return a.speed
+}
+
+func (a *Abilities) Invulnerable() bool {
+	return a.invulnerable > 0
+}
+
+// Restore puts the abilities back in phase with the invulnerability it had left, the speed of the phases already
+// used is applied again without using their abilities
+func (a *Abilities) Restore(phase, invulnerable int) {
+	if phase < 0 || phase > len(a.phases) {
+		panic(fmt.Sprintf("abilities cannot be restored in phase %d of %d", phase, len(a.phases)))
+	}
+	a.Reset()
+	for a.next < phase {
+		for _, ab := range a.phases[a.next].Abilities {
+			if ab.Kind == SpeedAbility {
+				a.speed = ab.Speed
+			}
+		}
+		a.next++
+	}
+	a.invulnerable = core.MaxInt(0, invulnerable)
+}
+
+func (a *Abilities) Reset() {
+	a.next, a.speed, a.invulnerable = 0, 100, 0
+}
+
+func (a *Abilities) Copy() *Abilities {
+	return &Abilities{a.phases, a.atlas, 0, 100, 0}
+}
+
+// Disrupt disables the towers in an area until d.Until
+func Disrupt(con core.Context, d Disruption) {
+	if con == nil {
+		return
+	}
+	ds, ok := con.Attribute(DisruptionsKey).(*Disruptions)
+	if !ok {
+		ds = &Disruptions{}
+		con.SetAttribute(DisruptionsKey, ds)
+	}
+	ds.active = append(ds.active, d)
+}
+
+// Disrupted reports whether a tower centered on p is disabled at the given tick
+func Disrupted(con core.Context, p core.Point, ticks int) bool {
+	if con == nil {
+		return false
+	}
+	ds, ok := con.Attribute(DisruptionsKey).(*Disruptions)
+	if !ok {
+		return false
+	}
+	ret, active := false, ds.active[:0]
+	for _, d := range ds.active {
+		if ticks >= d.Until {
+			continue
+		}
+		active = append(active, d)
+		ret = ret || p.Near(d.Point, d.Radius)
+	}
+	ds.active = active
+	return ret
+}
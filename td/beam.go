@@ -0,0 +1,206 @@
+package td
+
+import (
+	"tdgame/asset"
+	"tdgame/core"
+	"tdgame/graph"
+
+	"github.com/fogleman/gg"
+)
+
+type (
+	BeamAttributes struct {
+		Damage           int
+		Ramp             int // extra damage for every consecutive hit on the same target
+		MaxRamp          int `yaml:"maxRamp"` // most extra damage from ramping, 0 for no cap
+		Chains           int // additional enemies the beam jumps to from its target
+		ChainRadius      int `yaml:"chainRadius"`
+		Statuses         []StatusAttributes
+		DamageAttributes `yaml:",inline"`
+	}
+	// BeamTower locks onto a single enemy and damages it every Delay ticks for as long as it stays in range,
+	// the range of a beam tower is measured in tiles from the center of the tower.
+	BeamTower struct {
+		*TowerSpec
+		*core.LocationWrapper
+		g        graph.Graph
+		nodes    []*graph.Node
+		sprite   *asset.Sprite
+		t        *core.Ticker
+		target   Enemy
+		chain    []Enemy
+		hits     int
+		disabled bool
+		// percents of extra range and damage from the terrain under the tower
+		reach, damage int
+	}
+)
+
+var _ graph.Damager = (*BeamTower)(nil)
+
+func (t *BeamTower) Spec() *TowerSpec {
+	return t.TowerSpec
+}
+
+func (t *BeamTower) Process(ticks int, con core.Context) bool {
+	if t.disabled = Disrupted(con, t.Center(), ticks); t.disabled {
+		t.target, t.hits, t.chain = nil, 0, t.chain[:0]
+		return false
+	}
+	t.sprite.Process(ticks, con)
+	if !t.InRange(t.target) {
+		t.target, t.hits = t.acquire(), 0
+		t.t.Reset()
+	}
+	if t.target == nil {
+		t.chain = t.chain[:0]
+		return false
+	}
+	if t.t.Tick() {
+		t.t.Reset()
+		t.chain = t.Chain()
+		t.DoDamage(t.target, con)
+		for _, e := range t.chain {
+			t.DoDamage(e, con)
+		}
+		t.hits++
+	}
+	return false
+}
+
+func (t *BeamTower) acquire() Enemy {
+	for _, nd := range t.nodes {
+		for _, d := range nd.Damageables() {
+			if e, ok := d.(Enemy); ok && t.InRange(e) && CanTarget(t.Targets, e) && Visible(t.TowerSpec, e) {
+				return e
+			}
+		}
+	}
+	return nil
+}
+
+// InRange reports whether e is in play and inside of the donut described by the tower's range
+func (t *BeamTower) InRange(e Enemy) bool {
+	if !InPlay(e) || !Visible(t.TowerSpec, e) {
+		return false
+	}
+	dist := e.Center().DistanceSquared(t.Center())
+	return dist >= core.Square(t.Min*t.g.Spec().TileSize) && dist <= core.Square(t.Radius())
+}
+
+// Chain finds the enemies the beam jumps to, each jump goes to the closest enemy not already hit
+func (t *BeamTower) Chain() []Enemy {
+	chain, from := t.chain[:0], t.target
+	for len(chain) < t.Chains {
+		var next Enemy
+		best, center := -1, from.Center()
+		for _, d := range t.g.DamageablesWithin(center, t.ChainRadius) {
+			e, ok := d.(Enemy)
+			if !ok || e == t.target || !InPlay(e) || containsEnemy(chain, e) || !CanTarget(t.Targets, e) || !Visible(t.TowerSpec, e) {
+				continue
+			}
+			if dist := e.Center().DistanceSquared(center); best < 0 || dist < best {
+				next, best = e, dist
+			}
+		}
+		if next == nil {
+			break
+		}
+		chain, from = append(chain, next), next
+	}
+	return chain
+}
+
+func containsEnemy(es []Enemy, e Enemy) bool {
+	for _, o := range es {
+		if o == e {
+			return true
+		}
+	}
+	return false
+}
+
+// CurrentDamage is the damage of the next hit, the ramp is not capped when MaxRamp is 0
+func (t *BeamTower) CurrentDamage() int {
+	ramp := t.hits * t.Ramp
+	if t.MaxRamp > 0 {
+		ramp = core.MinInt(t.MaxRamp, ramp)
+	}
+	return (t.BeamAttributes.Damage + ramp) * (100 + t.damage) / 100
+}
+
+func (t *BeamTower) DoDamage(d graph.Damageable, con core.Context) {
+	hit(con, t.Name, d, t.CurrentDamage(), &t.BeamAttributes.DamageAttributes)
+	afflict(d, t.BeamAttributes.Statuses)
+}
+
+func (t *BeamTower) Radius() int {
+	return t.Max * t.g.Spec().TileSize * (100 + t.reach) / 100
+}
+
+func (t *BeamTower) Center() core.Point {
+	return t.Location().Center(t.g.Spec().TileSize)
+}
+
+func (t *BeamTower) Reach() int {
+	return t.Radius()
+}
+
+func (t *BeamTower) Near(col graph.Collider) bool {
+	return t.Center().Near(col.Center(), t.Radius()+col.Radius())
+}
+
+func (t *BeamTower) Spawn(pl *ParticleList) Particle {
+	return nil
+}
+
+func (t *BeamTower) Draw(con *gg.Context) {
+	center := t.Center()
+	if core.Grid {
+		con.SetRGBA(.9, .9, .9, 0.2)
+		con.DrawCircle(float64(center.X()), float64(center.Y()), float64(t.Radius()))
+		con.Fill()
+	}
+	t.sprite.Draw(con, t.Location())
+	drawDisabled(con, t.Location(), t.g.Spec().TileSize, t.disabled)
+	if t.target == nil {
+		return
+	}
+	from := center
+	for i := -1; i < len(t.chain); i++ {
+		e := t.target
+		if i >= 0 {
+			e = t.chain[i]
+		}
+		if !InPlay(e) {
+			break
+		}
+		to := e.Center()
+		con.DrawLine(float64(from.X()), float64(from.Y()), float64(to.X()), float64(to.Y()))
+		from = to
+	}
+	// the beam gets thicker as the damage ramps up
+	con.SetLineWidth(float64(2 + core.MinInt(t.hits, 4)))
+	con.SetRGBA(1, .2, .2, .8)
+	con.Stroke()
+}
+
+func (t *BeamTower) CopyAt(l core.Location, ta *TowerAtlas) Tower {
+	g := ta.graphs.Graph("map").(graph.CachedImageGraph)
+	mods := g.ModifiersAt(l.Center(g.TileSize))
+	return &BeamTower{
+		t.TowerSpec,
+		core.LocWrapper(l),
+		g,
+		// tiles are gathered one ring past the max range since the range is a radius from the tower's center
+		g.TilesAround(l.Point, core.Range{Min: 0, Max: t.Max*(100+mods.Range)/100 + 1}),
+		t.sprite.Copy().(*asset.Sprite),
+		core.NewTicker(t.t.Max()),
+		nil,
+		nil,
+		0,
+		false,
+		mods.Range,
+		mods.Damage,
+	}
+}
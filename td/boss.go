@@ -0,0 +1,73 @@
+package td
+
+import (
+	"image/color"
+	"tdgame/core"
+
+	"github.com/fogleman/gg"
+)
+
+type (
+	BossAttributes struct {
+		Phases []PhaseAttributes
+	}
+	// BossEnemy goes through its phases as it takes damage and has a health bar across the top of the map
+	BossEnemy struct {
+		*BasicEnemy
+	}
+)
+
+const (
+	BossBarHeight = 12
+)
+
+var (
+	_              Enemy = (*BossEnemy)(nil)
+	BossDeathColor       = color.RGBA{255, 90, 20, 230}
+)
+
+func (b *BossEnemy) Process(ticks int, con core.Context) bool {
+	if b.Destroyed() && con != nil {
+		// rings of fire on top of the usual death effect
+		center := core.Loc(b.Center(), 0)
+		for i := 1; i <= 3; i++ {
+			con.Add(core.EffectLayer, NewPulse(center, i*b.TileSize(), BossDeathColor))
+		}
+	}
+	return b.BasicEnemy.Process(ticks, con)
+}
+
+func (b *BossEnemy) Draw(con *gg.Context) {
+	b.BasicEnemy.Draw(con)
+	tile := b.TileSize()
+	width := float64(b.Graph().Width()*tile - 2*tile)
+	x, y := float64(tile), float64(BossBarHeight)
+	con.SetRGBA(0, 0, 0, .6)
+	con.DrawRectangle(x-2, y-2, width+4, BossBarHeight+4)
+	con.Fill()
+	con.SetRGBA(.8, .1, .1, .9)
+	con.DrawRectangle(x, y, width*float64(b.health)/float64(b.max), BossBarHeight)
+	con.Fill()
+	if b.shield.Shield() > 0 {
+		con.SetRGBA(.3, .7, 1, .9)
+		con.DrawRectangle(x, y+BossBarHeight-3, width*float64(b.shield.Shield())/float64(b.max), 3)
+		con.Fill()
+	}
+	// a tick for each phase threshold
+	con.SetRGBA(1, 1, 1, .9)
+	for _, p := range b.abilities.phases {
+		px := x + width*float64(p.Threshold)/100
+		con.DrawLine(px, y, px, y+BossBarHeight)
+	}
+	con.SetLineWidth(2)
+	con.Stroke()
+	con.DrawStringAnchored(string(b.Name), x+width/2, y+BossBarHeight/2, .5, .5)
+}
+
+func (b *BossEnemy) CopyAt(l core.Location) Enemy {
+	be := b.BasicEnemy.copy()
+	ret := &BossEnemy{be}
+	be.self = ret
+	be.TileLocation.Move(l, ret)
+	return ret
+}
@@ -0,0 +1,161 @@
+package td
+
+import (
+	"fmt"
+	"math/rand"
+	"tdgame/core"
+	"tdgame/graph"
+
+	"github.com/fogleman/gg"
+)
+
+type (
+	// DamageAttributes describe how the damage of a projectile or tower is mitigated
+	DamageAttributes struct {
+		Type           core.Kind `yaml:"damageType"`
+		Penetration    int       // armor ignored by the attack
+		CritChance     int       `yaml:"critChance"`     // percent chance of a critical hit
+		CritMultiplier int       `yaml:"critMultiplier"` // percent of damage dealt by a critical hit, defaults to 200
+		MinDamage      int       `yaml:"minDamage"`      // damage that is always dealt, at least 1
+	}
+	// Defense is the mitigation an enemy has at the moment it is hit
+	Defense struct {
+		Armor         int
+		Resistances   map[core.Kind]int // percent of damage of a type that is ignored, negative for weaknesses
+		Vulnerability int               // percent of extra damage taken
+	}
+	Hittable interface {
+		Hit(amount int, da *DamageAttributes) DamageEvent
+	}
+	// DamageEvent reports a single hit before and after mitigation
+	DamageEvent struct {
+		Source, Target, Type core.Kind
+		Raw, Final           int
+		Crit                 bool
+		core.Location        // the center of the target when it was hit
+	}
+	CombatStats struct {
+		Hits, Crits int
+		Raw, Final  map[core.Kind]int // damage by source
+	}
+	DamageText struct {
+		*core.LocationWrapper
+		text string
+		crit bool
+		t    *core.Ticker
+	}
+)
+
+const (
+	PhysicalDamage  core.Kind = "physical"
+	ExplosiveDamage core.Kind = "explosive"
+	FireDamage      core.Kind = "fire"
+	MagicDamage     core.Kind = "magic"
+	PierceDamage    core.Kind = "pierce"
+	// StatsKey is the context attribute that combat stats are recorded to
+	StatsKey         core.ContextKey = "stats"
+	DamageTextLength                 = 24
+)
+
+var (
+	ShowDamageText = true
+	Physical       = &DamageAttributes{Type: PhysicalDamage}
+)
+
+func (da *DamageAttributes) DamageType() core.Kind {
+	if da.Type == "" {
+		return PhysicalDamage
+	}
+	return da.Type
+}
+
+func (da *DamageAttributes) Crit() bool {
+	return da.CritChance > 0 && rand.Intn(100) < da.CritChance
+}
+
+// ResolveDamage computes the final damage of an attack against a defense. Critical hits scale the raw
+// damage, then armor less penetration is subtracted, then the resistance to the damage type and any
+// vulnerability are applied and finally the result is raised to the minimum damage of the attack.
+func ResolveDamage(amount int, da *DamageAttributes, def Defense, crit bool) DamageEvent {
+	ev := DamageEvent{Type: da.DamageType(), Raw: amount, Crit: crit}
+	if crit {
+		mult := da.CritMultiplier
+		if mult == 0 {
+			mult = 200
+		}
+		ev.Raw = amount * mult / 100
+	}
+	armor := core.MaxInt(0, def.Armor-da.Penetration)
+	final := core.MaxInt(0, ev.Raw-armor)
+	final = final * (100 - def.Resistances[ev.Type]) / 100
+	final = final * (100 + def.Vulnerability) / 100
+	ev.Final = core.MaxInt(core.MaxInt(1, da.MinDamage), final)
+	return ev
+}
+
+// hit damages d with all of the mitigation it supports and reports the result
+func hit(con core.Context, source core.Kind, d graph.Damageable, amount int, da *DamageAttributes) {
+	h, ok := d.(Hittable)
+	if !ok {
+		d.TakeDamage(amount)
+		return
+	}
+	ev := h.Hit(amount, da)
+	ev.Source = source
+	Report(con, ev)
+}
+
+// Report records a damage event to the combat stats of the context and shows its floating text
+func Report(con core.Context, ev DamageEvent) {
+	if con == nil {
+		return
+	}
+	if stats, ok := con.Attribute(StatsKey).(*CombatStats); ok {
+		stats.Record(ev)
+	}
+	if ShowDamageText {
+		con.Add(core.EffectLayer, NewDamageText(ev))
+	}
+}
+
+func NewCombatStats() *CombatStats {
+	return &CombatStats{0, 0, make(map[core.Kind]int), make(map[core.Kind]int)}
+}
+
+func (cs *CombatStats) Record(ev DamageEvent) {
+	cs.Hits++
+	if ev.Crit {
+		cs.Crits++
+	}
+	cs.Raw[ev.Source] += ev.Raw
+	cs.Final[ev.Source] += ev.Final
+}
+
+// Mitigated is the total damage from a source that was prevented by defenses
+func (cs *CombatStats) Mitigated(source core.Kind) int {
+	return cs.Raw[source] - cs.Final[source]
+}
+
+func NewDamageText(ev DamageEvent) *DamageText {
+	text := fmt.Sprint(ev.Final)
+	if ev.Crit {
+		text += "!"
+	}
+	return &DamageText{core.LocWrapper(core.Loc(ev.Point, 0)), text, ev.Crit, core.NewTicker(DamageTextLength)}
+}
+
+func (dt *DamageText) Process(ticks int, con core.Context) bool {
+	dt.SetLocation(dt.Location().North())
+	return dt.t.Tick()
+}
+
+func (dt *DamageText) Draw(con *gg.Context) {
+	alpha := 1 - float64(dt.t.Ticks())/DamageTextLength
+	if dt.crit {
+		con.SetRGBA(1, .85, .1, alpha)
+	} else {
+		con.SetRGBA(1, 1, 1, alpha)
+	}
+	l := dt.Location()
+	con.DrawStringAnchored(dt.text, float64(l.X()), float64(l.Y()), .5, .5)
+}
@@ -2,6 +2,7 @@ package td
 
 import (
 	"container/list"
+	"fmt"
 	"tdgame/animator"
 	"tdgame/asset"
 	"tdgame/core"
@@ -12,18 +13,36 @@ import (
 
 type (
 	EnemyAttributes struct {
-		Asset     core.Kind
-		Animation core.Kind
-		Effect    core.Kind
-		Health    int
-		Speed     int
-		Points    int
+		Asset             core.Kind
+		Animation         core.Kind
+		Effect            core.Kind
+		Health            int
+		Speed             int
+		Points            int
+		Armor             int
+		Resistances       map[core.Kind]int // percent of each damage type ignored
+		Immune            []core.Kind       // status effects that cannot be applied to the enemy
+		PoolSize          int               `yaml:"poolSize"`
+		PoolMax           int               `yaml:"poolMax"`   // most enemies of this kind alive at once, 0 for no limit
+		LeakTicks         int               `yaml:"leakTicks"` // ticks alive before a leak is reported, 0 for core.LeakTicks and -1 for never
+		SplitAttributes   `yaml:"split"`
+		FlyAttributes     `yaml:"fly"`
+		SupportAttributes `yaml:"support"`
+		BossAttributes    `yaml:"boss"`
+		Ability           AbilityAttributes // a single ability any enemy can use
+		Lives             int               // lives lost when the enemy makes it to the end of the path, at least 1
+		Stealth           bool              // towers can only target the enemy when it is detected
+		Regen             int               // hundredths of health regenerated every tick
+		Routing           core.Kind         // how the enemy picks a branch at a fork, random when empty
 	}
 	EnemySpec struct {
 		core.Meta
 		EnemyAttributes `yaml:"attributes"`
 	}
-	EnemyAtlas map[core.Kind]Enemy
+	EnemyAtlas struct {
+		enemies map[core.Kind]Enemy
+		pools   map[core.Kind]*core.Pool[Enemy]
+	}
 	Damageable interface {
 		Health() int
 		Damage(int)
@@ -36,101 +55,191 @@ type (
 		core.PoolItem
 		core.ListItem
 		core.Drawer
+		core.Releasable
 		Finalize() asset.Effect
 	}
 	Enemy interface {
+		Particle
 		CopyAt(l core.Location) Enemy
 		Spec() *EnemySpec
 		Damageable
+		graph.Damageable
+		Afflictable
+		Hittable
 		core.Locator
 		Active() bool
 		LocationAt(tick int) (core.Location, bool)
+		// Path is the index of the map path the enemy spawned on, SetPath puts it at the start of another one
+		Path() int
+		SetPath(i int)
+		// Segment is the run of the path the enemy is on, Follow puts it at the start of another one
+		Segment() *graph.Segment
+		Follow(s *graph.Segment)
+		// PathTick is how far along its path the enemy is, Seek moves it there
+		PathTick() int
+		Seek(tick int)
+		Flying() bool
+		Hidden() bool
+		// State saves the enemy, Restore puts a spawned enemy back the way it was saved
+		State() EnemyState
+		Restore(st EnemyState)
 	}
 	HealthBar struct {
 		max, health int
 	}
+	// ShieldBar absorbs damage before it reaches the HealthBar
+	ShieldBar struct {
+		max, shield int
+	}
+	Shieldable interface {
+		Shield(amount int)
+	}
 	BasicEnemy struct {
 		*EnemySpec
 		*graph.TileLocation
 		*HealthBar
-		anim   *animator.PrecalculatedAnimator
-		sprite *asset.Sprite
-		effect *asset.SpriteEffect
-		e      *list.Element
-		active bool
-	}
-	ParticlePool struct {
-		*core.BasicPool
+		shield *ShieldBar
+		anim   mover
+		// anims are the animators of every segment of the map, or of every path for enemies that fly
+		anims     []*animator.PrecalculatedAnimator
+		firsts    []*graph.Segment // the first segment of every path, nil for enemies that fly
+		walker    walker           // nil for enemies that follow the segments of the map or fly
+		seg       *graph.Segment
+		path      int
+		sprite    *asset.Sprite
+		effects   *asset.EffectPool
+		statuses  *Statuses
+		abilities *Abilities
+		e         *list.Element
+		active    bool
+		hidden    bool
+		// progress is the movement accumulated in hundredths of a pixel, step is how far to move this tick
+		progress, step int
+		// regen is the regeneration accumulated in hundredths of health, flash is the ticks left to flash for
+		regen, flash int
+		release      func()
+		// self is the enemy that is registered in the tiles, varieties that embed a BasicEnemy replace it
+		self Enemy
 	}
 	ParticleList struct {
 		*list.List
 	}
+	// EnemyState is everything needed to save an enemy and restore it later
+	EnemyState struct {
+		Name     core.Kind
+		Path     int
+		Segment  int // ID of the segment the enemy is on, -1 for enemies that do not follow segments
+		Tick     int // how far along its segment or path the enemy is
+		Health   int
+		Shield   int
+		Statuses []StatusState
+		// Phase is the number of ability phases already used, Invulnerable the ticks of invulnerability left
+		Phase, Invulnerable int
+	}
 )
 
 const (
-	EnemyType    = "enemy"
-	BasicVariety = "basic"
+	EnemyType       = "enemy"
+	BasicVariety    = "basic"
+	SplitterVariety = "splitter"
+	FlyingVariety   = "flying"
+	HealerVariety   = "healer"
+	ShieldVariety   = "shieldbearer"
+	BossVariety     = "boss"
 )
 
 func (es *EnemySpec) String() string {
 	return core.StructToYaml(es)
 }
 
-var _ core.DeclarationHandler = EnemyAtlas{}
+var _ core.DeclarationHandler = (*EnemyAtlas)(nil)
+var _ graph.Damageable = (*BasicEnemy)(nil)
+var _ Particle = (*BasicEnemy)(nil)
+
+func NewEnemyAtlas() *EnemyAtlas {
+	return &EnemyAtlas{
+		enemies: make(map[core.Kind]Enemy),
+		pools:   make(map[core.Kind]*core.Pool[Enemy]),
+	}
+}
+
+// Enemy creates a new enemy that is not managed by a pool
+func (ea *EnemyAtlas) Enemy(l core.Location, k core.Kind) Enemy {
+	return ea.enemies[k].CopyAt(l)
+}
 
-func NewEnemyAtlas() EnemyAtlas {
-	return EnemyAtlas{}
+// Spawn takes an enemy from its pool and moves it to l, it returns to the pool once it is removed from its layer.
+// Spawn returns nil when the pool is at its max size.
+func (ea *EnemyAtlas) Spawn(l core.Location, k core.Kind) Enemy {
+	e, ok := ea.pools[k].TryItem()
+	if !ok {
+		return nil
+	}
+	e.SetLocation(l)
+	return e
 }
 
-func (ea EnemyAtlas) Enemy(l core.Location, k core.Kind) Enemy {
-	return ea[k].CopyAt(l)
+// Restore spawns an enemy from its pool the way it was saved, it returns nil when the pool is at its max size
+func (ea *EnemyAtlas) Restore(st EnemyState) Enemy {
+	if _, ok := ea.pools[st.Name]; !ok {
+		panic(fmt.Sprintf("cannot restore enemy %s, it is not declared", st.Name))
+	}
+	e, ok := ea.pools[st.Name].TryItem()
+	if !ok {
+		return nil
+	}
+	e.Restore(st)
+	return e
 }
 
-func (ea EnemyAtlas) AddEnemy(e Enemy) {
-	ea[e.Spec().Name] = e
+func (ea *EnemyAtlas) Pool(k core.Kind) *core.Pool[Enemy] {
+	return ea.pools[k]
+}
+
+func (ea *EnemyAtlas) Pools() map[core.Kind]*core.Pool[Enemy] {
+	return ea.pools
+}
+
+func (ea *EnemyAtlas) AddEnemy(e Enemy) {
+	k, spec := e.Spec().Name, e.Spec()
+	ea.enemies[k] = e
+	ea.pools[k] = core.NewPool(spec.PoolSize, func() Enemy {
+		return e.CopyAt(core.ZeroLoc)
+	}).SetMax(spec.PoolMax).SetShrink(core.ShrinkToPeak(spec.PoolSize))
+	if spec.LeakTicks != 0 {
+		ea.pools[k].SetLeakTicks(spec.LeakTicks)
+	}
 }
 
-func (ea EnemyAtlas) Type() core.Kind {
+func (ea *EnemyAtlas) Type() core.Kind {
 	return EnemyType
 }
 
-func (ea EnemyAtlas) Match(pm *core.PreMeta) (core.Kinder, int) {
+func (ea *EnemyAtlas) Match(pm *core.PreMeta) (core.Kinder, int) {
 	switch pm.Variety {
-	case BasicVariety:
+	case BasicVariety, SplitterVariety, FlyingVariety, HealerVariety, ShieldVariety, BossVariety:
 		return &EnemySpec{}, 5
 	default:
 		panic("variety of enemy does not exist")
 	}
 }
 
-func (ea EnemyAtlas) PreLoad(d *core.Declarations) {
+func (ea *EnemyAtlas) PreLoad(d *core.Declarations) {
 
 }
 
-func (ea EnemyAtlas) Load(spec core.Kinder, d *core.Declarations) {
+func (ea *EnemyAtlas) Load(spec core.Kinder, d *core.Declarations) {
 	assets := d.Get("asset").(asset.AssetAtlas)
 	anims := d.Get("animator").(animator.AnimatorAtlas)
 	g := d.Get("graph").(graph.GraphAtlas).Graph("map").(graph.CachedImageGraph)
 	switch es := spec.(type) {
 	case *EnemySpec:
-		ea[es.Name] = EnemyFromSpec(es, assets, anims, g)
+		ea.AddEnemy(EnemyFromSpec(es, assets, anims, g, ea))
 	default:
 		panic("variety of enemy does not exist")
 	}
 }
 
-func NewParticlePool(size int, c func() Particle) *ParticlePool {
-	return &ParticlePool{core.NewPool(size, func() core.PoolItem { return c() })}
-}
-
-func (p *ParticlePool) Item() Particle {
-	return p.BasicPool.Item().(Particle)
-}
-
-func (p *ParticlePool) Return(par Particle) {
-	p.BasicPool.Return(par)
-}
-
 func NewParticleList() *ParticleList {
 	return &ParticleList{list.New()}
 }
@@ -198,23 +307,101 @@ func (hb *HealthBar) Heal(amount int) {
 }
 
 func (hb *HealthBar) Copy() *HealthBar {
-	return NewHealthBar(hb.health)
+	return NewHealthBar(hb.max)
 }
 
-func EnemyFromSpec(es *EnemySpec, assets asset.AssetAtlas, anims animator.AnimatorAtlas, g graph.CachedImageGraph) Enemy {
-	switch es.Variety {
-	case "basic":
-		sp := assets.Sprite(es.Asset)
-		return &BasicEnemy{
-			es,
-			g.TLoc(sp.Offset(), sp.Size()),
-			NewHealthBar(es.Health),
-			anims.PrecalculatedAnimator(es.Animation),
-			sp,
-			asset.NewSpriteEffect(core.ZeroLoc, assets.Sprite(es.Effect)),
-			nil,
-			false,
+// Raise tops the shield up to amount, shields from different sources do not stack
+func (sb *ShieldBar) Raise(amount int) {
+	sb.max = core.MaxInt(sb.max, amount)
+	sb.shield = core.MaxInt(sb.shield, amount)
+}
+
+// Absorb takes as much of amount as it can from the shield and returns the rest
+func (sb *ShieldBar) Absorb(amount int) int {
+	absorbed := core.MinInt(sb.shield, amount)
+	sb.shield -= absorbed
+	return amount - absorbed
+}
+
+func (sb *ShieldBar) Shield() int {
+	return sb.shield
+}
+
+func (sb *ShieldBar) Reset() {
+	sb.max, sb.shield = 0, 0
+}
+
+func EnemyFromSpec(es *EnemySpec, assets asset.AssetAtlas, anims animator.AnimatorAtlas, g graph.CachedImageGraph, ea *EnemyAtlas) Enemy {
+	sp := sprite(assets, es.Asset, g.TileSize)
+	var paths []*animator.PrecalculatedAnimator
+	var firsts []*graph.Segment
+	var w walker
+	if es.Variety == FlyingVariety {
+		for _, p := range g.Paths() {
+			paths = append(paths, flightPath(es, p))
+		}
+	} else if es.Routing == FlowRouting {
+		w = NewFlowWalker(g)
+	} else if m := g.Maze(); m != nil {
+		w = NewMazeWalker(m, g.TileSize)
+	} else {
+		for _, s := range g.Segments() {
+			paths = append(paths, anims.PrecalculatedAnimator(animator.SegmentAnimatorKind(es.Animation, s.ID)))
 		}
+		for _, p := range g.Paths() {
+			firsts = append(firsts, p.First)
+		}
+	}
+	be := &BasicEnemy{
+		es,
+		g.TLoc(sp.Offset(), sp.Size()),
+		NewHealthBar(es.Health),
+		&ShieldBar{},
+		nil,
+		paths,
+		firsts,
+		w,
+		nil,
+		0,
+		sp,
+		asset.NewEffectPool(es.PoolSize, asset.NewSpriteEffect(core.ZeroLoc, sprite(assets, es.Effect, g.TileSize))),
+		NewStatuses(es.Immune, assets, g.TileSize),
+		NewAbilities(es.Phases, es.Ability, ea),
+		nil,
+		false,
+		false,
+		0,
+		0,
+		0,
+		0,
+		nil,
+		nil,
+	}
+	be.SetPath(0)
+	switch es.Variety {
+	case BasicVariety:
+		be.self = be
+		return be
+	case SplitterVariety:
+		ret := &SplitterEnemy{be, ea}
+		be.self = ret
+		return ret
+	case FlyingVariety:
+		ret := &FlyingEnemy{be}
+		be.self = ret
+		return ret
+	case HealerVariety:
+		ret := &HealerEnemy{be, core.NewTicker(es.Cooldown)}
+		be.self = ret
+		return ret
+	case ShieldVariety:
+		ret := &ShieldEnemy{be, core.NewTicker(es.Cooldown)}
+		be.self = ret
+		return ret
+	case BossVariety:
+		ret := &BossEnemy{be}
+		be.self = ret
+		return ret
 	default:
 		panic("variety of enemy does not exist")
 	}
@@ -222,8 +409,9 @@ func EnemyFromSpec(es *EnemySpec, assets asset.AssetAtlas, anims animator.Animat
 
 func (e *BasicEnemy) Finalize() asset.Effect {
 	if e.Destroyed() {
-		e.effect.SetLocation(e.Location())
-		return e.effect
+		effect := e.effects.Item()
+		effect.SetLocation(e.Location())
+		return effect
 	}
 	return nil
 }
@@ -232,36 +420,183 @@ func (e *BasicEnemy) Spec() *EnemySpec {
 	return e.EnemySpec
 }
 
+// InPlay reports whether e is on the map and has not been destroyed, an enemy back in its pool is reset to full
+// health so towers and projectiles that still hold it must let it go
+func InPlay(e Enemy) bool {
+	return e != nil && e.Active() && !e.Destroyed()
+}
+
 func (e *BasicEnemy) Active() bool {
 	return e.active
 }
 
 func (e *BasicEnemy) Init() {
 	e.active = true
-	e.effect.Reset()
+	e.hidden = e.Stealth
 }
 
 func (e *BasicEnemy) Reset() {
 	e.active = false
 	e.sprite.Reset()
-	e.anim.Reset()
+	e.SetPath(0)
 	e.HealthBar.Reset()
+	e.shield.Reset()
+	e.statuses.Reset()
+	e.abilities.Reset()
+	e.progress, e.step = 0, 0
+	e.regen, e.flash = 0, 0
+	e.hidden = false
 	e.e = nil
-	e.SetLocation(core.ZeroLoc)
+	e.TileLocation.Clear(e.self)
+	e.TileLocation.SetLocation(core.ZeroLoc)
+}
+
+func (e *BasicEnemy) SetRelease(release func()) {
+	e.release = release
+}
+
+func (e *BasicEnemy) Release() {
+	if e.release != nil {
+		e.release()
+	}
+}
+
+// SetLocation moves the enemy and keeps the tiles it is registered in up to date for collisions
+func (e *BasicEnemy) SetLocation(l core.Location) {
+	e.TileLocation.Move(l, e.self)
+}
+
+func (e *BasicEnemy) TakeDamage(amount int) {
+	e.Hit(amount, Physical)
+}
+
+func (e *BasicEnemy) Defense() Defense {
+	return Defense{
+		core.MaxInt(0, e.Armor-e.statuses.Shred()),
+		e.Resistances,
+		e.statuses.Vulnerability(),
+	}
+}
+
+// Damage is absorbed by the enemy's shield before it is taken from its health, invulnerable enemies take none
+func (e *BasicEnemy) Damage(amount int) {
+	if e.abilities.Invulnerable() {
+		return
+	}
+	if amount > 0 {
+		e.flash = FlashTicks
+	}
+	e.HealthBar.Damage(e.shield.Absorb(amount))
+}
+
+func (e *BasicEnemy) Shield(amount int) {
+	e.shield.Raise(amount)
+}
+
+func (e *BasicEnemy) Hit(amount int, da *DamageAttributes) DamageEvent {
+	ev := ResolveDamage(amount, da, e.Defense(), da.Crit())
+	if e.abilities.Invulnerable() {
+		ev.Final = 0
+	}
+	e.Damage(ev.Final)
+	ev.Target, ev.Location = e.Name, core.Loc(e.Center(), e.Location().Rot())
+	return ev
+}
+
+func (e *BasicEnemy) Afflict(sa *StatusAttributes) {
+	e.statuses.Afflict(sa)
+}
+
+func (e *BasicEnemy) Statuses() *Statuses {
+	return e.statuses
 }
 
+// Process moves the enemy along its path, it is done once it is destroyed or makes it to the end of the path
 func (e *BasicEnemy) Process(ticks int, con core.Context) bool {
-	e.sprite.Process(ticks, con)
-	e.anim.Animate(e)
+	if e.Destroyed() {
+		if con != nil {
+			con.Add(core.EffectLayer, e.Finalize())
+		}
+		Killed(con, e)
+		return true
+	}
+	if e.Done() {
+		Escaped(con, e)
+		return true
+	}
+	e.hidden = e.Stealth && !Detected(con, e.Center())
+	e.flash = core.MaxInt(0, e.flash-1)
+	e.statuses.Process(ticks, con, e)
+	e.abilities.Process(ticks, con, e)
+	e.regenerate()
+	if !e.statuses.Stunned() {
+		e.sprite.Process(ticks, con)
+		e.progress += e.EnemySpec.Speed * e.statuses.SpeedPercent() * e.abilities.SpeedPercent() * e.terrainPercent() / 10000
+		e.step, e.progress = e.progress/100, e.progress%100
+		e.anim.Animate(e)
+		if e.anim.Done() && !e.Done() {
+			e.Follow(ChooseSegment(con, e.Routing, e.seg.Next))
+		}
+	}
+	QueueHealthBar(con, e.HealthBarState())
 	return false
 }
 
+func (e *BasicEnemy) regenerate() {
+	if e.Regen <= 0 || e.Destroyed() || e.health == e.max {
+		e.regen = 0
+		return
+	}
+	e.regen += e.Regen
+	e.Heal(e.regen / 100)
+	e.regen %= 100
+}
+
+// HealthBarState is a snapshot of everything the health bar of the enemy shows
+func (e *BasicEnemy) HealthBarState() HealthBarState {
+	l := e.Location()
+	if e.self.Flying() {
+		l = core.Loc(l.Subtract(core.Pt(0, e.Altitude)), l.Rot())
+	}
+	return HealthBarState{l, e.health, e.max, e.shield.Shield(), e.Defense().Armor, e.TileSize()}
+}
+
+// Speed is the number of pixels the enemy moves during the current tick
 func (e *BasicEnemy) Speed() int {
-	return e.EnemySpec.Speed
+	return e.step
+}
+
+// Hidden reports whether the enemy is stealthy and was not in a detected tile as of its last tick
+func (e *BasicEnemy) Hidden() bool {
+	return e.hidden
+}
+
+func (e *BasicEnemy) drawSprite(con *gg.Context, l core.Location) {
+	if e.hidden {
+		e.sprite.DrawFaded(con, l)
+	} else {
+		e.sprite.Draw(con, l)
+	}
+	if e.flash > 0 {
+		e.sprite.DrawFlash(con, l)
+	}
 }
 
 func (e *BasicEnemy) Draw(con *gg.Context) {
-	e.sprite.Draw(con, e.Location())
+	e.drawSprite(con, e.Location())
+	e.statuses.Draw(con, e.Location())
+	e.drawInvulnerable(con, e.Location())
+}
+
+func (e *BasicEnemy) drawInvulnerable(con *gg.Context, l core.Location) {
+	if !e.abilities.Invulnerable() {
+		return
+	}
+	c := l.Center(e.TileSize())
+	con.SetRGBA(1, .85, .2, .8)
+	con.SetLineWidth(2)
+	con.DrawCircle(float64(c.X()), float64(c.Y()), float64(e.Radius()))
+	con.Stroke()
 }
 
 func (e *BasicEnemy) Elem() *list.Element {
@@ -272,12 +607,102 @@ func (e *BasicEnemy) SetElem(el *list.Element) {
 	e.e = el
 }
 
+// Done reports whether the enemy has left the map, an enemy at a fork is not done until it has chosen a
+// segment to follow
 func (e *BasicEnemy) Done() bool {
-	return e.anim.Done()
+	return e.anim.Done() && (e.seg == nil || e.seg.Leaves())
+}
+
+func (e *BasicEnemy) Flying() bool {
+	return false
+}
+
+func (e *BasicEnemy) Path() int {
+	return e.path
+}
+
+func (e *BasicEnemy) SetPath(i int) {
+	e.path = i
+	if e.walker != nil {
+		e.anim = e.walker
+		e.walker.Spawn(i)
+		return
+	}
+	if e.firsts == nil {
+		e.anim = e.anims[i]
+		e.anim.Reset()
+		return
+	}
+	e.Follow(e.firsts[i])
+}
+
+func (e *BasicEnemy) Segment() *graph.Segment {
+	return e.seg
+}
+
+func (e *BasicEnemy) Follow(s *graph.Segment) {
+	if s == nil {
+		return
+	}
+	e.seg, e.anim = s, e.anims[s.ID]
+	e.anim.Reset()
+}
+
+func (e *BasicEnemy) PathTick() int {
+	return e.anim.Ticks()
+}
+
+func (e *BasicEnemy) Seek(tick int) {
+	e.anim.Seek(tick)
+	if l, ok := e.anim.LocationOffset(0); ok {
+		e.SetLocation(l)
+	}
+}
+
+func (e *BasicEnemy) State() EnemyState {
+	seg := -1
+	if e.seg != nil {
+		seg = e.seg.ID
+	}
+	return EnemyState{e.Name, e.path, seg, e.PathTick(), e.health, e.shield.Shield(), e.statuses.State(),
+		e.abilities.Phase(), e.abilities.invulnerable}
+}
+
+// Restore puts the enemy on the path and segment it was saved on with the health, shield, statuses and ability
+// phase it had
+func (e *BasicEnemy) Restore(st EnemyState) {
+	if st.Health <= 0 || st.Health > e.max {
+		panic(fmt.Sprintf("enemy %s cannot be restored with %d of its %d health", e.Name, st.Health, e.max))
+	}
+	if paths := len(e.Graph().(graph.CachedImageGraph).Paths()); st.Path < 0 || st.Path >= paths {
+		panic(fmt.Sprintf("enemy %s cannot be restored on path %d of a map with %d", e.Name, st.Path, paths))
+	}
+	e.SetPath(st.Path)
+	if st.Segment >= 0 {
+		segs := e.Graph().(graph.CachedImageGraph).Segments()
+		if st.Segment >= len(segs) {
+			panic(fmt.Sprintf("enemy %s cannot be restored on segment %d of a map with %d", e.Name, st.Segment, len(segs)))
+		}
+		e.Follow(segs[st.Segment])
+	}
+	e.Seek(st.Tick)
+	e.health = st.Health
+	e.shield.Raise(st.Shield)
+	e.statuses.Restore(st.Statuses)
+	e.abilities.Restore(st.Phase, st.Invulnerable)
 }
 
 func (e *BasicEnemy) LocationAt(tick int) (core.Location, bool) {
-	return e.anim.LocationOffset(tick * e.Speed())
+	return e.anim.LocationOffset(tick * e.EnemySpec.Speed * e.statuses.SpeedPercent() * e.abilities.SpeedPercent() * e.terrainPercent() / 1000000)
+}
+
+// terrainPercent is the percentage of its speed the terrain under the enemy lets it walk at, flying enemies
+// fly over any terrain at full speed
+func (e *BasicEnemy) terrainPercent() int {
+	if e.self.Flying() || e.Graph() == nil {
+		return 100
+	}
+	return e.Graph().ModifiersAt(e.Center()).Speed
 }
 
 func (e *BasicEnemy) Radius() int {
@@ -289,16 +714,47 @@ func (e *BasicEnemy) Near(col graph.Collider) bool {
 }
 
 func (e *BasicEnemy) CopyAt(l core.Location) Enemy {
+	ret := e.copy()
+	ret.self = ret
+	ret.TileLocation.Move(l, ret)
+	return ret
+}
+
+// copy creates a new BasicEnemy that is not registered in any tiles yet
+func (e *BasicEnemy) copy() *BasicEnemy {
+	paths := make([]*animator.PrecalculatedAnimator, len(e.anims))
+	for i, p := range e.anims {
+		paths[i] = p.Copy().(*animator.PrecalculatedAnimator)
+	}
+	var w walker
+	if e.walker != nil {
+		w = e.walker.Copy()
+	}
 	ret := &BasicEnemy{
 		e.EnemySpec,
 		e.TileLocation.Copy(),
 		e.HealthBar.Copy(),
-		e.anim.Copy().(*animator.PrecalculatedAnimator),
+		&ShieldBar{},
+		nil,
+		paths,
+		e.firsts,
+		w,
+		nil,
+		0,
 		e.sprite.Copy().(*asset.Sprite),
-		e.effect.CopyAt(core.ZeroLoc).(*asset.SpriteEffect),
+		e.effects,
+		e.statuses.Copy(),
+		e.abilities.Copy(),
 		nil,
 		false,
+		false,
+		0,
+		0,
+		0,
+		0,
+		nil,
+		nil,
 	}
-	ret.TileLocation.Move(l, ret)
+	ret.SetPath(0)
 	return ret
 }
@@ -0,0 +1,124 @@
+package td
+
+import (
+	"tdgame/animator"
+	"tdgame/core"
+	"tdgame/graph"
+)
+
+type (
+	// FlowWalker steers by the flow field of the exit of its path, it picks the tile to walk into next as it
+	// enters a tile so changes to the field are followed right away. Tiles keeps the tiles already walked so
+	// ticks stay the number of pixels walked like they are for animators.
+	FlowWalker struct {
+		g      graph.CachedImageGraph
+		f      *graph.FlowField
+		tiles  []core.Point
+		walked int
+	}
+)
+
+var _ walker = (*FlowWalker)(nil)
+
+func NewFlowWalker(g graph.CachedImageGraph) *FlowWalker {
+	return &FlowWalker{g: g}
+}
+
+// Spawn puts the walker at the tile before the start of path i
+func (w *FlowWalker) Spawn(i int) {
+	p := w.g.Path(i)
+	w.f, w.tiles, w.walked = w.g.Flow(i), append(w.tiles[:0], p.InitialPoint(), p.Start), 0
+}
+
+func (w *FlowWalker) Copy() walker {
+	return &FlowWalker{w.g, w.f, append([]core.Point(nil), w.tiles...), w.walked}
+}
+
+// next is the tile the field leads to from p, prev is the tile before p. The walk ends on the tile after the
+// exit even when the exit is inside of the map.
+func (w *FlowWalker) next(prev, p core.Point) (core.Point, bool) {
+	if prev == w.f.Exit {
+		return p, false
+	}
+	d, ok := w.f.Flow(p)
+	if !ok {
+		return p, false
+	}
+	return p.Neighbor(d), true
+}
+
+// reach adds tiles from the field until tile i+1 is known, it reports false when the field ends before that
+func (w *FlowWalker) reach(i int) bool {
+	for len(w.tiles) < i+2 {
+		last := len(w.tiles) - 1
+		n, ok := w.next(w.tiles[last-1], w.tiles[last])
+		if !ok {
+			return false
+		}
+		w.tiles = append(w.tiles, n)
+	}
+	return true
+}
+
+// Done reports whether the walker has walked past the exit, a walker on a tile that cannot reach the exit waits
+// there until it can
+func (w *FlowWalker) Done() bool {
+	last := len(w.tiles) - 1
+	return w.walked >= last*w.g.TileSize && w.tiles[last-1] == w.f.Exit
+}
+
+func (w *FlowWalker) Ticks() int {
+	return w.walked
+}
+
+func (w *FlowWalker) Seek(tick int) {
+	w.walked = core.MaxInt(0, tick)
+	if !w.reach(w.walked / w.g.TileSize) {
+		w.walked = (len(w.tiles) - 1) * w.g.TileSize
+	}
+}
+
+func (w *FlowWalker) Reset() {
+	w.tiles, w.walked = w.tiles[:2], 0
+}
+
+// LocationOffset looks ahead along the field without committing to the tiles it passes
+func (w *FlowWalker) LocationOffset(tick int) (core.Location, bool) {
+	walked := w.walked + tick
+	i, last := walked/w.g.TileSize, len(w.tiles)-1
+	if i < last {
+		return stepLocation(w.tiles[i], w.tiles[i+1], walked%w.g.TileSize, w.g.TileSize), true
+	}
+	prev, from := w.tiles[last-1], w.tiles[last]
+	for j := last; ; j++ {
+		to, ok := w.next(prev, from)
+		if !ok {
+			return core.Loc(core.Pt(-2048, -2048), 0), false
+		}
+		if j == i {
+			return stepLocation(from, to, walked%w.g.TileSize, w.g.TileSize), true
+		}
+		prev, from = from, to
+	}
+}
+
+func (w *FlowWalker) Animate(a animator.Animatable) {
+	i := w.walked / w.g.TileSize
+	if !w.reach(i) {
+		return
+	}
+	if w.tiles[i] != w.f.Exit && w.f.Cost(w.tiles[i+1]) == graph.Impassable {
+		// the tile ahead was blocked, turn around where the walker is and take the new flow from the tile it came
+		// from. The step back starts as far from the walker as the walker is from that tile.
+		frac := w.walked % w.g.TileSize
+		w.tiles = append(w.tiles[:i+2], w.tiles[i])
+		w.walked = (i+2)*w.g.TileSize - frac
+		if !w.reach(i + 1) {
+			return
+		}
+	}
+	if l, ok := w.LocationOffset(0); ok {
+		a.SetLocation(l)
+	}
+	w.walked += a.Speed()
+}
@@ -0,0 +1,92 @@
+package td
+
+import (
+	"math"
+	"tdgame/animator"
+	"tdgame/core"
+	"tdgame/graph"
+
+	"github.com/fogleman/gg"
+)
+
+type (
+	FlyAttributes struct {
+		Curve    int // pixels the flight path bows away from a straight line at its middle, negative bows the other way
+		Altitude int // pixels the enemy is drawn above its shadow
+	}
+	// FlyingEnemy flies over the map from the start of the path to its end instead of following the path
+	FlyingEnemy struct {
+		*BasicEnemy
+	}
+)
+
+const (
+	// Targets of towers
+	GroundTargets core.Kind = "ground"
+	AirTargets    core.Kind = "air"
+	AllTargets    core.Kind = "both"
+)
+
+var _ Enemy = (*FlyingEnemy)(nil)
+
+// CanTarget reports whether something that targets the given kind of enemies can hit d, towers target
+// ground enemies by default
+func CanTarget(targets core.Kind, d graph.Damageable) bool {
+	flying := false
+	if e, ok := d.(Enemy); ok {
+		flying = e.Flying()
+	}
+	switch targets {
+	case GroundTargets, "":
+		return !flying
+	case AirTargets:
+		return flying
+	case AllTargets:
+		return true
+	default:
+		panic("targets of tower does not exist")
+	}
+}
+
+// LayerOf is the layer an enemy is processed and drawn in, flying enemies are drawn over ground enemies
+func LayerOf(e Enemy) core.Layer {
+	if e.Flying() {
+		return core.AirLayer
+	}
+	return core.EnemyLayer
+}
+
+// flightPath is a straight or gently curved line from the start of a path of the map to its end
+func flightPath(es *EnemySpec, p *graph.Path) *animator.PrecalculatedAnimator {
+	start, end := p.StartLoc().Point, p.EndLoc().Point
+	dx, dy := float64(end.X()-start.X()), float64(end.Y()-start.Y())
+	length := math.Max(1, math.Hypot(dx, dy))
+	mid := core.Pt((start.X()+end.X())/2, (start.Y()+end.Y())/2)
+	bow := float64(es.Curve) / length
+	control := mid.Add(core.Pt(int(-dy*bow), int(dx*bow)))
+	return animator.AnimatorFromCurve(es.Name, start, control, end)
+}
+
+func (f *FlyingEnemy) Flying() bool {
+	return true
+}
+
+func (f *FlyingEnemy) Draw(con *gg.Context) {
+	l := f.Location()
+	c, r := l.Center(f.TileSize()), float64(f.Size.X())/3
+	con.SetRGBA(0, 0, 0, .3)
+	con.DrawEllipse(float64(c.X()), float64(c.Y()), r, r/2)
+	con.Fill()
+	air := core.Loc(l.Subtract(core.Pt(0, f.Altitude)), l.Rot())
+	f.drawSprite(con, air)
+	f.statuses.Draw(con, air)
+	f.drawInvulnerable(con, air)
+}
+
+func (f *FlyingEnemy) CopyAt(l core.Location) Enemy {
+	be := f.BasicEnemy.copy()
+	ret := &FlyingEnemy{be}
+	be.self = ret
+	be.TileLocation.Move(l, ret)
+	return ret
+}
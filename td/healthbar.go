@@ -0,0 +1,126 @@
+package td
+
+import (
+	"image/color"
+	"math"
+	"tdgame/core"
+
+	"github.com/fogleman/gg"
+)
+
+type (
+	// HealthBarState is a snapshot of an enemy for its health bar, it is copied so that enemies can go back to
+	// their pools before the bars are drawn
+	HealthBarState struct {
+		core.Location
+		Health, Max, Shield, Armor int
+		Tile                       int // size in pixels of the tiles of the map, the bar spans the tile
+	}
+	// HealthBarRenderer draws the health bars of every damaged enemy in one pass, bars are grouped by color so
+	// that every color is filled once no matter how many enemies there are
+	HealthBarRenderer struct {
+		pending, ready []HealthBarState
+	}
+)
+
+const (
+	// HealthBarsKey is the context attribute of the health bar renderer
+	HealthBarsKey    core.ContextKey = "healthbars"
+	HealthBarBuckets                 = 10
+	HealthBarHeight                  = 4
+	// FlashTicks is how long an enemy flashes after it takes damage
+	FlashTicks   = 4
+	maxArmorPips = 8
+)
+
+var (
+	ShowHealthBars  = true
+	healthBarColors = func() (ret [HealthBarBuckets + 1]color.RGBA) {
+		// red at no health through yellow to green at full health
+		for i := range ret {
+			p := float64(i) / HealthBarBuckets
+			r, g := 1.0, 1.0
+			if p > .5 {
+				r = 2 - 2*p
+			} else {
+				g = 2 * p
+			}
+			ret[i] = color.RGBA{uint8(r * 230), uint8(g * 200), 40, 230}
+		}
+		return
+	}()
+)
+
+var _ core.GameObject = (*HealthBarRenderer)(nil)
+
+// QueueHealthBar adds a health bar to the renderer of the context, enemies at full health without a shield
+// do not get one
+func QueueHealthBar(con core.Context, s HealthBarState) {
+	if !ShowHealthBars || con == nil || (s.Health >= s.Max && s.Shield == 0) {
+		return
+	}
+	if hbr, ok := con.Attribute(HealthBarsKey).(*HealthBarRenderer); ok {
+		hbr.pending = append(hbr.pending, s)
+	}
+}
+
+func NewHealthBarRenderer() *HealthBarRenderer {
+	return &HealthBarRenderer{make([]HealthBarState, 0), make([]HealthBarState, 0)}
+}
+
+// Process makes the bars queued since the last tick the ones that are drawn
+func (hbr *HealthBarRenderer) Process(ticks int, con core.Context) bool {
+	if con != nil {
+		con.SetAttribute(HealthBarsKey, hbr)
+	}
+	hbr.pending, hbr.ready = hbr.ready[:0], hbr.pending
+	return false
+}
+
+func barRect(s HealthBarState) (x, y, w float64) {
+	margin := s.Tile / 8
+	return float64(s.X() + margin), float64(s.Y() + 4), float64(s.Tile - 2*margin)
+}
+
+func (hbr *HealthBarRenderer) Draw(con *gg.Context) {
+	if !ShowHealthBars || len(hbr.ready) == 0 {
+		return
+	}
+	for _, s := range hbr.ready {
+		x, y, w := barRect(s)
+		con.DrawRectangle(x-1, y-1, w+2, HealthBarHeight+2)
+	}
+	con.SetRGBA(0, 0, 0, .6)
+	con.Fill()
+	for bucket, c := range healthBarColors {
+		for _, s := range hbr.ready {
+			if s.Health*HealthBarBuckets/core.MaxInt(1, s.Max) != bucket {
+				continue
+			}
+			x, y, w := barRect(s)
+			con.DrawRectangle(x, y, w*float64(s.Health)/float64(s.Max), HealthBarHeight)
+		}
+		con.SetColor(c)
+		con.Fill()
+	}
+	// shields extend the bar past the current health up to the end of the bar
+	for _, s := range hbr.ready {
+		if s.Shield == 0 {
+			continue
+		}
+		x, y, w := barRect(s)
+		start := w * float64(s.Health) / float64(s.Max)
+		con.DrawRectangle(x+start, y, math.Min(w-start, w*float64(s.Shield)/float64(s.Max)), HealthBarHeight)
+	}
+	con.SetRGBA(.3, .7, 1, .9)
+	con.Fill()
+	// a pip under the bar for each point of armor
+	for _, s := range hbr.ready {
+		x, y, _ := barRect(s)
+		for i := 0; i < core.MinInt(s.Armor, maxArmorPips); i++ {
+			con.DrawRectangle(x+float64(i*5), y+HealthBarHeight+2, 4, 2)
+		}
+	}
+	con.SetRGBA(.75, .75, .75, .9)
+	con.Fill()
+}
@@ -0,0 +1,158 @@
+package td
+
+import (
+	"tdgame/animator"
+	"tdgame/core"
+	"tdgame/graph"
+)
+
+type (
+	// mover moves an enemy along its way through the map, a precalculated animator on maps with paths and a
+	// walker on maze maps or for enemies that follow the flow of the map
+	mover interface {
+		Animate(a animator.Animatable)
+		Done() bool
+		Ticks() int
+		Seek(tick int)
+		LocationOffset(tick int) (core.Location, bool)
+		Reset()
+	}
+	// walker is a mover that finds its own way from the spawn of a path to an exit
+	walker interface {
+		mover
+		Spawn(path int)
+		Copy() walker
+	}
+	// basic is implemented by every enemy that embeds a BasicEnemy
+	basic interface {
+		basic() *BasicEnemy
+	}
+	// MazeWalker walks the route the maze finds for it one pixel per tick of speed, when the maze changes it
+	// finds a new route from the tile it is walking into. Routes keep the tiles already walked so ticks stay
+	// the distance along the route like they are for animators.
+	MazeWalker struct {
+		m                *graph.Maze
+		tile             int // size in pixels of the tiles of the maze
+		route            []core.Point
+		walked           int
+		version, cleared int
+	}
+)
+
+var _ walker = (*MazeWalker)(nil)
+var _ mover = (*animator.PrecalculatedAnimator)(nil)
+
+func NewMazeWalker(m *graph.Maze, tile int) *MazeWalker {
+	return &MazeWalker{m: m, tile: tile}
+}
+
+// Start puts the walker at the beginning of a route
+func (w *MazeWalker) Start(route []core.Point) {
+	w.route, w.walked = route, 0
+	w.version, w.cleared = w.m.Version()
+}
+
+// Spawn puts the walker at the tile before spawn i of the maze
+func (w *MazeWalker) Spawn(i int) {
+	w.Start(w.m.SpawnRoute(i))
+}
+
+func (w *MazeWalker) Copy() walker {
+	return &MazeWalker{w.m, w.tile, w.route, w.walked, w.version, w.cleared}
+}
+
+func (w *MazeWalker) length() int {
+	return (len(w.route) - 1) * w.tile
+}
+
+func (w *MazeWalker) Done() bool {
+	return w.walked >= w.length()
+}
+
+func (w *MazeWalker) Ticks() int {
+	return w.walked
+}
+
+func (w *MazeWalker) Seek(tick int) {
+	w.walked = core.MinInt(core.MaxInt(0, tick), w.length())
+}
+
+func (w *MazeWalker) Reset() {
+	w.walked = 0
+}
+
+func (w *MazeWalker) LocationOffset(tick int) (core.Location, bool) {
+	walked := w.walked + tick
+	if walked >= w.length() {
+		return core.Loc(core.Pt(-2048, -2048), 0), false
+	}
+	i := walked / w.tile
+	return stepLocation(w.route[i], w.route[i+1], walked%w.tile, w.tile), true
+}
+
+// stepLocation is frac pixels along the step from a tile to the one next to it on a map with tiles tile pixels
+// wide, facing the way of the step
+func stepLocation(from, to core.Point, frac, tile int) core.Location {
+	d := core.S
+	for _, dir := range core.Directions {
+		if from.Neighbor(dir) == to {
+			d = dir
+		}
+	}
+	return core.Loc(from.Scale(tile).Add(to.Subtract(from).Scale(frac)), d.Rotation())
+}
+
+func (w *MazeWalker) Animate(a animator.Animatable) {
+	if w.Done() {
+		return
+	}
+	w.reroute()
+	if l, ok := w.LocationOffset(0); ok {
+		a.SetLocation(l)
+	}
+	w.walked += a.Speed()
+}
+
+// reroute finds a new way from the tile the walker is heading into once the maze has changed, a walker whose
+// next tile was blocked turns around where it is and walks back to the tile it came from. Routes are shared
+// between walkers so the new route is always a new slice.
+func (w *MazeWalker) reroute() {
+	i, frac := w.walked/w.tile, w.walked%w.tile
+	if i+1 >= len(w.route) || !w.m.Affected(w.route[i+1:], w.version, w.cleared) {
+		w.version, w.cleared = w.m.Version()
+		return
+	}
+	from := i + 1
+	if w.m.Blocked(w.route[from]) {
+		from = i
+	}
+	route, ok := w.m.Route(w.route[from])
+	if !ok {
+		// the tile the walker is on cannot reach an exit, keep going until the maze opens up again
+		return
+	}
+	if from == i {
+		// the step back from the blocked tile starts as far from the walker as the walker is from the tile it
+		// came from, so the walker is at the same spot facing the other way
+		w.route = append(w.route[:i+2:i+2], route...)
+		w.walked = (i+2)*w.tile - frac
+	} else {
+		w.route = append(w.route[:from:from], route...)
+	}
+	w.version, w.cleared = w.m.Version()
+}
+
+func (e *BasicEnemy) basic() *BasicEnemy {
+	return e
+}
+
+// walkLike puts e where o is on the way o walks, enemies that do not walk their own way are left alone
+func walkLike(e, o Enemy) {
+	eb, ok := e.(basic)
+	ob, ok2 := o.(basic)
+	if !ok || !ok2 || eb.basic().walker == nil || ob.basic().walker == nil {
+		return
+	}
+	w := ob.basic().walker.Copy()
+	eb.basic().walker, eb.basic().anim = w, w
+}
@@ -2,6 +2,8 @@ package td
 
 import (
 	"container/list"
+	"math"
+	"sort"
 	"tdgame/animator"
 	"tdgame/asset"
 	"tdgame/core"
@@ -12,33 +14,84 @@ import (
 
 type (
 	ProjectileAttributes struct {
-		Asset           core.Kind
-		Effect          core.Kind
-		PoolSize        int `yaml:"poolSize"`
-		Speed           int
-		Damage          int
-		ExplosionRadius int `yaml:"explosionRadius"`
+		Variety          core.Kind // bullet, homing, piercing, bouncing or ballistic
+		Asset            core.Kind
+		Effect           core.Kind
+		PoolSize         int `yaml:"poolSize"`
+		PoolMax          int `yaml:"poolMax"` // most projectiles in the air at once, 0 for no limit
+		Speed            int
+		Damage           int
+		ExplosionRadius  int       `yaml:"explosionRadius"` // pixels from the center of impact that take damage
+		Falloff          core.Kind // how damage drops off from the center of the explosion
+		MaxTargets       int       `yaml:"maxTargets"` // closest enemies damaged by the explosion, 0 for no limit
+		TurnRate         int       `yaml:"turnRate"`   // degrees a homing projectile turns per tick
+		Pierce           int       // enemies a piercing projectile passes through
+		Bounces          int       // times a bouncing projectile finds a new target
+		BounceRadius     int       `yaml:"bounceRadius"`
+		ArcHeight        int       `yaml:"arcHeight"` // peak height in pixels of a ballistic projectile
+		Statuses         []StatusAttributes
+		DamageAttributes `yaml:",inline"`
 	}
 	Projectile interface {
 		Particle
 		Spec() *ProjectileAttributes
 		CopyAt(l core.Location, g graph.Graph) Projectile
 		UpdateTarget(anim *animator.PrecalculatedAnimator)
+		// Fire launches the projectile from a point at e, which is predicted to be at the point at in ticks
+		Fire(from core.Point, e Enemy, at core.Point, ticks int)
+		// Boost raises the damage of the projectile by a percent until it is reset
+		Boost(percent int)
 	}
 	Bullet struct {
 		*ProjectileAttributes
 		*graph.TileLocation
-		asset  asset.Asset
-		anim   *animator.PrecalculatedAnimator
-		el     *list.Element
-		active bool
-		effect *asset.SpriteEffect
+		asset   asset.Asset
+		anim    *animator.PrecalculatedAnimator
+		el      *list.Element
+		active  bool
+		targets core.Kind // the targets of the tower that fired the bullet
+		source  core.Kind // the name of the tower that fired the bullet, its hits are reported as the tower's
+		effects *asset.EffectPool
+		release func()
+		boost   int // percent of extra damage from the terrain under the tower that fired it
 	}
 	ProjectileList struct {
 		*list.List
 	}
 )
 
+const (
+	BulletVariety    core.Kind = "bullet"
+	HomingVariety    core.Kind = "homing"
+	PiercingVariety  core.Kind = "piercing"
+	BouncingVariety  core.Kind = "bouncing"
+	BallisticVariety core.Kind = "ballistic"
+	NoFalloff        core.Kind = "none"
+	LinearFalloff    core.Kind = "linear"
+	QuadraticFalloff core.Kind = "quadratic"
+)
+
+var _ graph.Damager = (*Bullet)(nil)
+
+// DamageAt scales the damage of the projectile by its falloff for something distSquared from the center of
+// an explosion with the given radius
+func (p *ProjectileAttributes) DamageAt(distSquared, radius int) int {
+	ratio := 0.0
+	if radius > 0 {
+		ratio = math.Min(1, math.Sqrt(float64(distSquared))/float64(radius))
+	}
+	switch p.Falloff {
+	case NoFalloff, "":
+		return p.Damage
+	case LinearFalloff:
+		return int(math.Round(float64(p.Damage) * (1 - ratio)))
+	case QuadraticFalloff:
+		return int(math.Round(float64(p.Damage) * (1 - ratio*ratio)))
+	default:
+		panic("falloff of projectile does not exist")
+	}
+}
+
 func NewProjectileList() *ProjectileList {
 	return &ProjectileList{list.New()}
 }
@@ -69,7 +122,7 @@ func (pl *ProjectileList) For(f func(idx int, p Projectile) bool) {
 	}
 }
 
-func NewBullet(spec *ProjectileAttributes, a asset.Asset, tl *graph.TileLocation, anim *animator.PrecalculatedAnimator, effect *asset.SpriteEffect) Projectile {
+func NewBullet(spec *ProjectileAttributes, a asset.Asset, tl *graph.TileLocation, anim *animator.PrecalculatedAnimator, targets, source core.Kind, effects *asset.EffectPool) Projectile {
 	ret := &Bullet{
 		spec,
 		tl,
@@ -77,11 +130,34 @@ func NewBullet(spec *ProjectileAttributes, a asset.Asset, tl *graph.TileLocation
 		anim,
 		nil,
 		false,
-		effect,
+		targets,
+		source,
+		effects,
+		nil,
+		0,
 	}
 	return ret
 }
 
+// NewProjectile creates a projectile of the variety declared in spec fired by the tower named source
+func NewProjectile(spec *ProjectileAttributes, a asset.Asset, tl *graph.TileLocation, targets, source core.Kind, effects *asset.EffectPool) Projectile {
+	b := NewBullet(spec, a, tl, nil, targets, source, effects).(*Bullet)
+	switch spec.Variety {
+	case BulletVariety, "":
+		return b
+	case HomingVariety:
+		return &HomingProjectile{b, nil, 0, 0, 0, nil, false}
+	case PiercingVariety:
+		return &PiercingProjectile{b, make([]graph.Damageable, 0)}
+	case BouncingVariety:
+		return &BouncingProjectile{b, nil, make([]graph.Damageable, 0), 0}
+	case BallisticVariety:
+		return &BallisticProjectile{b}
+	default:
+		panic("variety of projectile does not exist")
+	}
+}
+
 func (b *Bullet) Spec() *ProjectileAttributes {
 	return b.ProjectileAttributes
 }
@@ -91,36 +167,124 @@ func (b *Bullet) Speed() int {
 }
 
 func (b *Bullet) Process(ticks int, con core.Context) bool {
-	if !b.Done() {
-		b.asset.Process(ticks, con)
-		b.anim.Animate(b)
+	if b.Done() {
+		return true
+	}
+	b.asset.Process(ticks, con)
+	b.anim.Animate(b)
+	if b.Done() {
+		b.Impact(con)
+		b.finish(con)
 	}
 	return false
 }
 
+func (b *Bullet) finish(con core.Context) {
+	if con != nil {
+		con.Add(core.EffectLayer, b.Finalize())
+	}
+}
+
+func (b *Bullet) Boost(percent int) {
+	b.boost = percent
+}
+
+func (b *Bullet) Fire(from core.Point, e Enemy, at core.Point, ticks int) {
+	b.LocationWrapper.SetLocation(core.Loc(from, 0))
+	b.Line(from, at, ticks)
+}
+
+// Line points the bullet along a line, the bullet's animator is reused when it has one
+func (b *Bullet) Line(from, to core.Point, ticks int) {
+	if b.anim == nil {
+		b.anim = animator.AnimatorFromLine(from, to, ticks)
+	} else {
+		b.anim.Line(from, to, ticks)
+	}
+}
+
+// Detonate explodes the projectile if it has an explosion radius, otherwise only the target is damaged
+func (b *Bullet) Detonate(con core.Context, target Enemy) {
+	if b.ExplosionRadius > 0 || target == nil {
+		b.Impact(con)
+	} else if InPlay(target) {
+		b.DoDamage(target, con)
+	}
+}
+
+// Impact damages everything within the explosion radius of the bullet, only the tiles that the explosion
+// overlaps are searched for targets
+func (b *Bullet) Impact(con core.Context) {
+	center := b.Center()
+	targets := b.Targets(b.Graph().DamageablesWithin(center, b.Radius()))
+	if b.MaxTargets > 0 && len(targets) > b.MaxTargets {
+		sort.Slice(targets, func(i, j int) bool {
+			return targets[i].Center().DistanceSquared(center) < targets[j].Center().DistanceSquared(center)
+		})
+		targets = targets[:b.MaxTargets]
+	}
+	for _, d := range targets {
+		b.DoDamage(d, con)
+	}
+}
+
+// Targets filters out what the bullet cannot hit
+func (b *Bullet) Targets(ds []graph.Damageable) []graph.Damageable {
+	ret := ds[:0]
+	for _, d := range ds {
+		if CanTarget(b.targets, d) {
+			ret = append(ret, d)
+		}
+	}
+	return ret
+}
+
 func (b *Bullet) Active() bool {
 	return b.active
 }
 
 func (b *Bullet) Init() {
 	b.active = true
-	b.effect.Reset()
 }
 
 func (b *Bullet) Reset() {
 	b.active = false
 	b.asset.Reset()
 	b.LocationWrapper.SetLocation(core.Loc(core.Pt(-100, -100), 0)) // put off screen for the moment
-	b.anim = nil
+	if b.anim != nil {
+		b.anim.Reset()
+	}
 	b.el = nil
+	b.boost = 0
+}
+
+func (b *Bullet) SetRelease(release func()) {
+	b.release = release
+}
+
+func (b *Bullet) Release() {
+	if b.release != nil {
+		b.release()
+	}
 }
 
 func (b *Bullet) Draw(con *gg.Context) {
 	b.asset.Draw(con, b.Location())
 }
 
-func (b *Bullet) Near(o core.Point) bool {
-	return b.Location().Near(o, core.Square(b.ExplosionRadius))
+// Radius is the explosion radius, projectiles without one only hit what they touch
+func (b *Bullet) Radius() int {
+	return core.MaxInt(b.ExplosionRadius, b.Size.X()/2)
+}
+
+func (b *Bullet) Near(col graph.Collider) bool {
+	return b.Center().Near(col.Center(), b.Radius())
+}
+
+func (b *Bullet) DoDamage(d graph.Damageable, con core.Context) {
+	dist := d.Center().DistanceSquared(b.Center())
+	hit(con, b.source, d, b.DamageAt(dist, b.Radius())*(100+b.boost)/100, &b.DamageAttributes)
+	afflict(d, b.ProjectileAttributes.Statuses)
 }
 
 func (b *Bullet) Elem() *list.Element {
@@ -136,12 +300,13 @@ func (b *Bullet) Done() bool {
 }
 
 func (b *Bullet) Finalize() asset.Effect {
-	b.effect.SetLocation(core.Loc(b.Location().Add(core.Pt(24, 24)), 0))
-	return b.effect
+	effect := b.effects.Item()
+	effect.SetLocation(core.Loc(b.Location().Add(b.Offset), 0))
+	return effect
 }
 
 func (b *Bullet) CopyAt(l core.Location, g graph.Graph) Projectile {
-	return NewBullet(b.ProjectileAttributes, b.asset.Copy(), b.TileLocation.Copy(), nil, b.effect.CopyAt(core.ZeroLoc).(*asset.SpriteEffect))
+	return NewProjectile(b.ProjectileAttributes, b.asset.Copy(), b.TileLocation.Copy(), b.targets, b.source, b.effects)
 }
 
 func (b *Bullet) UpdateTarget(anim *animator.PrecalculatedAnimator) {
@@ -153,6 +318,6 @@ func (b *Bullet) Destination() core.Location {
 	return l
 }
 
-func (b *Bullet) Effect() asset.Effect {
-	return b.effect
+func (b *Bullet) Effects() *asset.EffectPool {
+	return b.effects
 }
@@ -0,0 +1,229 @@
+package td
+
+import (
+	"math"
+	"tdgame/core"
+	"tdgame/graph"
+
+	"github.com/fogleman/gg"
+)
+
+type (
+	// HomingProjectile steers toward its target every tick, turning at most TurnRate degrees
+	HomingProjectile struct {
+		*Bullet
+		target        Enemy
+		x, y, heading float64 // heading is in degrees clockwise from east
+		life          *core.Ticker
+		done          bool
+	}
+	// PiercingProjectile flies in a straight line past its target, damaging up to Pierce+1 enemies
+	PiercingProjectile struct {
+		*Bullet
+		hit []graph.Damageable
+	}
+	// BouncingProjectile jumps to the closest enemy it has not hit after each hit, up to Bounces times
+	BouncingProjectile struct {
+		*Bullet
+		target  Enemy
+		hit     []graph.Damageable
+		bounces int
+	}
+	// BallisticProjectile follows a parabolic arc to a point on the ground, casting a shadow as it goes
+	BallisticProjectile struct {
+		*Bullet
+	}
+)
+
+var (
+	_ Projectile = (*Bullet)(nil)
+	_ Projectile = (*HomingProjectile)(nil)
+	_ Projectile = (*PiercingProjectile)(nil)
+	_ Projectile = (*BouncingProjectile)(nil)
+	_ Projectile = (*BallisticProjectile)(nil)
+)
+
+func angle(from, to core.Point) float64 {
+	return gg.Degrees(math.Atan2(float64(to.Y()-from.Y()), float64(to.X()-from.X())))
+}
+
+func alive(d graph.Damageable) bool {
+	e, ok := d.(Enemy)
+	return !ok || InPlay(e)
+}
+
+func containsDamageable(ds []graph.Damageable, d graph.Damageable) bool {
+	for _, o := range ds {
+		if o == d {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *HomingProjectile) Fire(from core.Point, e Enemy, at core.Point, ticks int) {
+	h.LocationWrapper.SetLocation(core.Loc(from, 0))
+	h.target, h.done = e, false
+	h.x, h.y, h.heading = float64(from.X()), float64(from.Y()), angle(from, at)
+	// a homing projectile gets a few times as long as a straight shot would before it fizzles out
+	if h.life == nil {
+		h.life = core.NewTicker(ticks * 3)
+	} else {
+		h.life.Restart(ticks * 3)
+	}
+}
+
+func (h *HomingProjectile) Process(ticks int, con core.Context) bool {
+	if h.done {
+		return true
+	}
+	h.asset.Process(ticks, con)
+	tracking := InPlay(h.target)
+	if tracking {
+		rate := float64(h.TurnRate)
+		if rate <= 0 {
+			rate = 180
+		}
+		turn := math.Remainder(angle(h.Location().Point, h.target.Location().Point)-h.heading, 360)
+		h.heading += math.Max(-rate, math.Min(rate, turn))
+	}
+	rad := gg.Radians(h.heading)
+	h.x += math.Cos(rad) * float64(h.ProjectileAttributes.Speed)
+	h.y += math.Sin(rad) * float64(h.ProjectileAttributes.Speed)
+	h.LocationWrapper.SetLocation(core.Loc(core.Pt(int(h.x), int(h.y)), int(h.heading)+90))
+	if tracking && h.target.Near(h) {
+		h.done = true
+		h.Detonate(con, h.target)
+		h.finish(con)
+	} else if h.life.Tick() {
+		h.done = true
+		h.Detonate(con, nil)
+		h.finish(con)
+	}
+	return false
+}
+
+func (h *HomingProjectile) Done() bool {
+	return h.done
+}
+
+func (h *HomingProjectile) Reset() {
+	h.Bullet.Reset()
+	h.target, h.done = nil, false
+}
+
+func (p *PiercingProjectile) Fire(from core.Point, e Enemy, at core.Point, ticks int) {
+	p.hit = p.hit[:0]
+	// keep going past the target for as long again as it took to get there
+	p.Bullet.Fire(from, e, at.Add(at.Subtract(from)), ticks*2)
+}
+
+func (p *PiercingProjectile) Process(ticks int, con core.Context) bool {
+	if p.Done() {
+		return true
+	}
+	p.asset.Process(ticks, con)
+	p.anim.Animate(p)
+	center := p.Center()
+	for _, d := range p.Targets(p.Graph().DamageablesWithin(center, p.Radius()+p.TileSize()/2)) {
+		if len(p.hit) > p.Pierce {
+			break
+		}
+		if !alive(d) || containsDamageable(p.hit, d) || !d.Near(p) {
+			continue
+		}
+		p.DoDamage(d, con)
+		p.hit = append(p.hit, d)
+	}
+	if p.Done() {
+		p.finish(con)
+	}
+	return false
+}
+
+func (p *PiercingProjectile) Done() bool {
+	return p.anim.Done() || len(p.hit) > p.Pierce
+}
+
+func (p *PiercingProjectile) Reset() {
+	p.Bullet.Reset()
+	p.hit = p.hit[:0]
+}
+
+func (b *BouncingProjectile) Fire(from core.Point, e Enemy, at core.Point, ticks int) {
+	b.target, b.bounces, b.hit = e, b.Bounces, b.hit[:0]
+	b.Bullet.Fire(from, e, at, ticks)
+}
+
+func (b *BouncingProjectile) Process(ticks int, con core.Context) bool {
+	if b.Done() {
+		return true
+	}
+	b.asset.Process(ticks, con)
+	b.anim.Animate(b)
+	if !b.anim.Done() {
+		return false
+	}
+	b.Detonate(con, b.target)
+	b.hit = append(b.hit, b.target)
+	if next := b.next(); next != nil && b.bounces > 0 {
+		from, speed := b.Location().Point, core.MaxInt(1, b.ProjectileAttributes.Speed)
+		if at, ticks, ok := Intercept(from, next, core.Range{Min: 1, Max: b.BounceRadius/speed + 1}, speed); ok {
+			b.bounces--
+			b.target = next
+			b.Line(from, at, ticks)
+			return false
+		}
+	}
+	b.target = nil
+	b.finish(con)
+	return false
+}
+
+// next is the closest enemy in play to the projectile that it has not hit yet
+func (b *BouncingProjectile) next() Enemy {
+	var ret Enemy
+	best, center := -1, b.Center()
+	for _, d := range b.Targets(b.Graph().DamageablesWithin(center, b.BounceRadius)) {
+		e, ok := d.(Enemy)
+		if !ok || !InPlay(e) || containsDamageable(b.hit, d) {
+			continue
+		}
+		if dist := e.Center().DistanceSquared(center); best < 0 || dist < best {
+			ret, best = e, dist
+		}
+	}
+	return ret
+}
+
+func (b *BouncingProjectile) Done() bool {
+	return b.target == nil
+}
+
+func (b *BouncingProjectile) Reset() {
+	b.Bullet.Reset()
+	b.target, b.bounces, b.hit = nil, 0, b.hit[:0]
+}
+
+// Height is how far above the ground the projectile is at the current point of its arc
+func (b *BallisticProjectile) Height() float64 {
+	if b.anim == nil {
+		return 0
+	}
+	p := b.anim.Progress()
+	return 4 * float64(b.ArcHeight) * p * (1 - p)
+}
+
+func (b *BallisticProjectile) Draw(con *gg.Context) {
+	ground, height := b.Location(), b.Height()
+	c, r := ground.Center(b.TileSize()), float64(b.Size.X())/2
+	// the shadow shrinks as the projectile climbs
+	scale := 1.0
+	if b.ArcHeight > 0 {
+		scale = 1 - height/float64(2*b.ArcHeight)
+	}
+	con.SetRGBA(0, 0, 0, .3)
+	con.DrawEllipse(float64(c.X()), float64(c.Y()), r*scale, r*scale/2)
+	con.Fill()
+	b.asset.Draw(con, core.Loc(ground.Subtract(core.Pt(0, int(height))), ground.Rot()))
+}
@@ -3,18 +3,39 @@ package td
 import "tdgame/core"
 
 type (
+	// Round spawns its enemies one at a time, it is over once every enemy has been spawned and every enemy
+	// alive, including any spawned by other enemies, has been killed or escaped. Starts is the spawn of each
+	// path of the map and Spawns is the path each enemy takes, enemies without one take turns on every path.
 	Round struct {
 		core.GameObjectNoop
 		Cur, Delay, Round, Points int
 		T                         *core.Ticker
-		Enemies                   []Enemy
+		Enemies                   []core.Kind
+		Atlas                     *EnemyAtlas
+		Starts                    []core.Location
+		Spawns                    []int
+		Alive, Earned, Escaped    int
+		LivesLost                 int
 	}
 )
 
+const (
+	// RoundKey is the context attribute of the round being played
+	RoundKey core.ContextKey = "round"
+)
+
 var _ core.GameObject = (*Round)(nil)
 
 func (r *Round) Process(ticks int, con core.Context) bool {
-	r.T.Tick()
+	if con != nil {
+		con.SetAttribute(RoundKey, r)
+	}
+	if e := r.Spawn(); e != nil && con != nil {
+		con.Add(LayerOf(e), e)
+	}
+	if !r.T.Done() {
+		r.T.Tick()
+	}
 	return false
 }
 
@@ -22,12 +43,64 @@ func (r *Round) Done() bool {
 	return r.Cur == len(r.Enemies)
 }
 
+func (r *Round) Over() bool {
+	return r.Done() && r.Alive == 0
+}
+
+// Spawn takes the next enemy of the round from its pool once the delay between enemies has passed, the round
+// waits while the pool is at its max size
 func (r *Round) Spawn() Enemy {
-	if r.T.Done() {
-		ret := r.Enemies[r.Cur]
+	if !r.Done() && r.T.Done() {
+		p := r.SpawnPath(r.Cur)
+		ret := r.Atlas.Spawn(r.Starts[p], r.Enemies[r.Cur])
+		if ret == nil {
+			// wait for an enemy to return to the pool
+			return nil
+		}
+		ret.SetPath(p)
 		r.Cur++
-		r.T = core.NewTicker(r.Delay)
+		r.Alive++
+		r.T.Restart(r.Delay)
 		return ret
 	}
 	return nil
 }
+
+// SpawnPath is the path the i-th enemy of the round takes
+func (r *Round) SpawnPath(i int) int {
+	if i < len(r.Spawns) {
+		return r.Spawns[i]
+	}
+	return i % len(r.Starts)
+}
+
+func round(con core.Context) *Round {
+	if con == nil {
+		return nil
+	}
+	r, _ := con.Attribute(RoundKey).(*Round)
+	return r
+}
+
+// Spawned counts an enemy that was spawned by something other than the round
+func Spawned(con core.Context, e Enemy) {
+	if r := round(con); r != nil {
+		r.Alive++
+	}
+}
+
+// Killed awards the bounty of e to the round
+func Killed(con core.Context, e Enemy) {
+	if r := round(con); r != nil {
+		r.Alive--
+		r.Earned += e.Spec().Points
+	}
+}
+
+func Escaped(con core.Context, e Enemy) {
+	if r := round(con); r != nil {
+		r.Alive--
+		r.Escaped++
+		r.LivesLost += core.MaxInt(1, e.Spec().Lives)
+	}
+}
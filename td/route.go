@@ -0,0 +1,152 @@
+package td
+
+import (
+	"math/rand"
+	"tdgame/core"
+	"tdgame/graph"
+
+	"github.com/fogleman/gg"
+)
+
+type (
+	// CoverageGrid counts the towers that can reach each tile, like the detection grid it is only recomputed
+	// after a tower has been added
+	CoverageGrid struct {
+		g        graph.Graph
+		sources  []detectionSource
+		coverage [][]int
+		dirty    bool
+	}
+)
+
+const (
+	RandomRouting   core.Kind = "random"   // weighted by the branches of the map
+	ShortestRouting core.Kind = "shortest" // fewest tiles left to an exit
+	SafestRouting   core.Kind = "safest"   // fewest towers covering the tiles ahead
+	FlowRouting     core.Kind = "flow"     // steers by the flow field of the map instead of following segments
+	// CoverageKey is the context attribute of the coverage grid
+	CoverageKey core.ContextKey = "coverage"
+	// RouteDepth is how many forks ahead safe routing looks
+	RouteDepth = 4
+)
+
+var _ core.GameObject = (*CoverageGrid)(nil)
+
+// ChooseSegment picks the segment an enemy follows at a fork
+func ChooseSegment(con core.Context, routing core.Kind, choices []*graph.Segment) *graph.Segment {
+	switch routing {
+	case RandomRouting, "":
+		total := 0
+		for _, s := range choices {
+			total += s.Weight
+		}
+		n := rand.Intn(total)
+		for _, s := range choices {
+			if n -= s.Weight; n < 0 {
+				return s
+			}
+		}
+		return choices[len(choices)-1]
+	case ShortestRouting:
+		return best(choices, func(s *graph.Segment) (int, int) { return s.Remaining, 0 })
+	case SafestRouting:
+		cg := coverage(con)
+		if cg == nil {
+			return ChooseSegment(con, ShortestRouting, choices)
+		}
+		// the remaining distance breaks ties between equally covered routes
+		return best(choices, func(s *graph.Segment) (int, int) {
+			return cg.Downstream(s, RouteDepth), s.Remaining
+		})
+	default:
+		panic("routing of enemy does not exist")
+	}
+}
+
+// best is the choice with the lowest score, the second part of a score only breaks ties of the first and the
+// earliest choice wins ties of both
+func best(choices []*graph.Segment, score func(*graph.Segment) (int, int)) *graph.Segment {
+	ret := choices[0]
+	low, tie := score(ret)
+	for _, s := range choices[1:] {
+		if sc, t := score(s); sc < low || sc == low && t < tie {
+			ret, low, tie = s, sc, t
+		}
+	}
+	return ret
+}
+
+func coverage(con core.Context) *CoverageGrid {
+	if con == nil {
+		return nil
+	}
+	cg, _ := con.Attribute(CoverageKey).(*CoverageGrid)
+	return cg
+}
+
+func NewCoverageGrid(g graph.Graph) *CoverageGrid {
+	coverage := make([][]int, g.Height())
+	for i := range coverage {
+		coverage[i] = make([]int, g.Width())
+	}
+	return &CoverageGrid{g, make([]detectionSource, 0), coverage, false}
+}
+
+// AddTower adds a tower that can hit enemies up to reach pixels from center
+func (cg *CoverageGrid) AddTower(center core.Point, reach int) {
+	cg.sources = append(cg.sources, detectionSource{center, reach})
+	cg.dirty = true
+}
+
+func (cg *CoverageGrid) Process(ticks int, con core.Context) bool {
+	if con != nil {
+		con.SetAttribute(CoverageKey, cg)
+	}
+	if !cg.dirty {
+		return false
+	}
+	for _, row := range cg.coverage {
+		for i := range row {
+			row[i] = 0
+		}
+	}
+	for _, s := range cg.sources {
+		for x := 0; x < cg.g.Width(); x++ {
+			for y := 0; y < cg.g.Height(); y++ {
+				if tile := cg.g.Spec().TileSize; core.Pt(x, y).Scale(tile).Center(tile).Near(s.Point, s.radius) {
+					cg.coverage[y][x]++
+				}
+			}
+		}
+	}
+	cg.dirty = false
+	return false
+}
+
+// Coverage is the number of towers that can reach the tile
+func (cg *CoverageGrid) Coverage(tile core.Point) int {
+	if tile.X() < 0 || tile.Y() < 0 || tile.Y() >= len(cg.coverage) || tile.X() >= len(cg.coverage[0]) {
+		return 0
+	}
+	return cg.coverage[tile.Y()][tile.X()]
+}
+
+// Downstream is the coverage of the tiles of s and of the least covered route after it, up to depth forks ahead
+func (cg *CoverageGrid) Downstream(s *graph.Segment, depth int) int {
+	ret := 0
+	for _, tile := range s.Tiles {
+		ret += cg.Coverage(tile)
+	}
+	if depth == 0 || len(s.Next) == 0 {
+		return ret
+	}
+	least := -1
+	for _, n := range s.Next {
+		if c := cg.Downstream(n, depth-1); least < 0 || c < least {
+			least = c
+		}
+	}
+	return ret + least
+}
+
+func (cg *CoverageGrid) Draw(con *gg.Context) {}
@@ -0,0 +1,58 @@
+package td
+
+import "tdgame/core"
+
+type (
+	SplitAttributes struct {
+		Children []core.Kind // enemies spawned where the splitter dies
+		Spread   int         // path ticks between each child
+	}
+	// SplitterEnemy spawns its children from the enemy pools when it is destroyed, the children pick up the
+	// path where the splitter left off
+	SplitterEnemy struct {
+		*BasicEnemy
+		atlas *EnemyAtlas
+	}
+)
+
+var _ Enemy = (*SplitterEnemy)(nil)
+
+func (s *SplitterEnemy) Process(ticks int, con core.Context) bool {
+	if s.Destroyed() {
+		s.Split(con)
+	}
+	return s.BasicEnemy.Process(ticks, con)
+}
+
+// Split spawns the children of the splitter
+func (s *SplitterEnemy) Split(con core.Context) {
+	spawnChildren(con, s.atlas, s, s.Children, s.Spread)
+}
+
+// spawnChildren spawns enemies where parent is that continue along its segment, each spread path ticks behind
+// the last, children whose pool is at its max size are not spawned
+func spawnChildren(con core.Context, atlas *EnemyAtlas, parent Enemy, children []core.Kind, spread int) {
+	tick := parent.PathTick()
+	for i, k := range children {
+		c := atlas.Spawn(parent.Location(), k)
+		if c == nil {
+			continue
+		}
+		c.SetPath(parent.Path())
+		c.Follow(parent.Segment())
+		walkLike(c, parent)
+		c.Seek(tick - i*spread)
+		if con != nil {
+			con.Add(LayerOf(c), c)
+		}
+		Spawned(con, c)
+	}
+}
+
+func (s *SplitterEnemy) CopyAt(l core.Location) Enemy {
+	be := s.BasicEnemy.copy()
+	ret := &SplitterEnemy{be, s.atlas}
+	be.self = ret
+	be.TileLocation.Move(l, ret)
+	return ret
+}
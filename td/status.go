@@ -0,0 +1,285 @@
+package td
+
+import (
+	"fmt"
+	"image/color"
+	"tdgame/asset"
+	"tdgame/core"
+	"tdgame/graph"
+
+	"github.com/fogleman/gg"
+)
+
+type (
+	// StatusAttributes declares a timed effect that a projectile or tower applies to the enemies it hits
+	StatusAttributes struct {
+		Kind      core.Kind
+		Duration  int // ticks the status lasts
+		Magnitude int // percent for slow and vulnerable, armor for shred, damage per interval for poison and burn
+		Interval  int // ticks between damage for poison and burn
+		MaxStacks int `yaml:"maxStacks"`
+		Stacking  core.Kind
+		Overlay   core.Kind // optional sprite drawn over the enemy instead of a tint
+	}
+	Afflictable interface {
+		Afflict(sa *StatusAttributes)
+	}
+	Status struct {
+		StatusAttributes
+		t, interval *core.Ticker
+		stacks      int
+		overlay     *asset.Sprite
+	}
+	// StatusState is everything needed to save a Status and restore it later
+	StatusState struct {
+		StatusAttributes `yaml:",inline"`
+		Total            int
+		Elapsed          int
+		IntervalElapsed  int `yaml:"intervalElapsed"`
+		Stacks           int
+	}
+	Statuses struct {
+		immune []core.Kind
+		active []*Status
+		assets asset.AssetAtlas
+		tile   int // size in pixels of the tiles of the map the statuses are drawn on
+	}
+)
+
+const (
+	SlowStatus       core.Kind = "slow"
+	PoisonStatus     core.Kind = "poison"
+	BurnStatus       core.Kind = "burn"
+	StunStatus       core.Kind = "stun"
+	ShredStatus      core.Kind = "shred"
+	VulnerableStatus core.Kind = "vulnerable"
+	// Stacking rules
+	RefreshStacking core.Kind = "refresh" // restart the duration and keep the strongest magnitude
+	StackStacking   core.Kind = "stack"   // restart the duration and add a stack up to MaxStacks
+	ExtendStacking  core.Kind = "extend"  // add the duration to what is remaining
+	IgnoreStacking  core.Kind = "ignore"  // do nothing while the status is active
+)
+
+var (
+	DefaultStacking = map[core.Kind]core.Kind{
+		SlowStatus:       RefreshStacking,
+		PoisonStatus:     StackStacking,
+		BurnStatus:       RefreshStacking,
+		StunStatus:       IgnoreStacking,
+		ShredStatus:      StackStacking,
+		VulnerableStatus: RefreshStacking,
+	}
+	StatusTints = map[core.Kind]color.RGBA{
+		SlowStatus:       {80, 160, 255, 90},
+		PoisonStatus:     {60, 220, 60, 90},
+		BurnStatus:       {255, 120, 20, 90},
+		StunStatus:       {255, 255, 120, 110},
+		ShredStatus:      {140, 140, 140, 90},
+		VulnerableStatus: {200, 60, 200, 90},
+	}
+)
+
+func afflict(d graph.Damageable, statuses []StatusAttributes) {
+	if a, ok := d.(Afflictable); ok {
+		for i := range statuses {
+			a.Afflict(&statuses[i])
+		}
+	}
+}
+
+func (sa *StatusAttributes) StackRule() core.Kind {
+	if sa.Stacking == "" {
+		return DefaultStacking[sa.Kind]
+	}
+	return sa.Stacking
+}
+
+func newStatus(sa StatusAttributes, assets asset.AssetAtlas, tile int) *Status {
+	var overlay *asset.Sprite
+	if sa.Overlay != "" {
+		overlay = sprite(assets, sa.Overlay, tile)
+	}
+	return &Status{
+		sa,
+		core.NewTicker(sa.Duration),
+		core.NewTicker(core.MaxInt(1, sa.Interval)),
+		1,
+		overlay,
+	}
+}
+
+// Strength is the magnitude of the status taking all of its stacks into account
+func (s *Status) Strength() int {
+	return s.Magnitude * s.stacks
+}
+
+func (s *Status) Process(ticks int, con core.Context, hb Damageable, amplify func(int) int) bool {
+	if s.overlay != nil {
+		s.overlay.Process(ticks, con)
+	}
+	if (s.Kind == PoisonStatus || s.Kind == BurnStatus) && s.interval.Tick() {
+		s.interval.Reset()
+		hb.Damage(amplify(s.Strength()))
+	}
+	return s.t.Tick()
+}
+
+func (s *Status) Reapply(sa *StatusAttributes) {
+	switch sa.StackRule() {
+	case RefreshStacking:
+		s.t = core.NewTicker(sa.Duration)
+		s.Magnitude = core.MaxInt(s.Magnitude, sa.Magnitude)
+	case StackStacking:
+		s.t = core.NewTicker(sa.Duration)
+		s.stacks = core.MinInt(s.stacks+1, core.MaxInt(1, sa.MaxStacks))
+	case ExtendStacking:
+		remaining := s.t.Max() - s.t.Ticks()
+		s.t = core.NewTicker(remaining + sa.Duration)
+	case IgnoreStacking:
+	default:
+		panic("stacking rule of status does not exist")
+	}
+}
+
+func (s *Status) State() StatusState {
+	return StatusState{s.StatusAttributes, s.t.Max(), s.t.Ticks(), s.interval.Ticks(), s.stacks}
+}
+
+// Draw draws the status over the tile at l of a map with tiles tile pixels wide
+func (s *Status) Draw(con *gg.Context, l core.Location, tile int) {
+	if s.overlay != nil {
+		s.overlay.Draw(con, l)
+		return
+	}
+	c := l.Center(tile)
+	con.SetColor(StatusTints[s.Kind])
+	con.DrawCircle(float64(c.X()), float64(c.Y()), float64(tile)/3)
+	con.Fill()
+}
+
+func NewStatuses(immune []core.Kind, assets asset.AssetAtlas, tile int) *Statuses {
+	return &Statuses{immune, make([]*Status, 0), assets, tile}
+}
+
+func (ss *Statuses) Immune(k core.Kind) bool {
+	for _, imm := range ss.immune {
+		if imm == k {
+			return true
+		}
+	}
+	return false
+}
+
+func (ss *Statuses) Status(k core.Kind) *Status {
+	for _, s := range ss.active {
+		if s.Kind == k {
+			return s
+		}
+	}
+	return nil
+}
+
+func (ss *Statuses) Afflict(sa *StatusAttributes) {
+	if ss.Immune(sa.Kind) || sa.Duration <= 0 {
+		return
+	}
+	if s := ss.Status(sa.Kind); s != nil {
+		s.Reapply(sa)
+	} else {
+		ss.active = append(ss.active, newStatus(*sa, ss.assets, ss.tile))
+	}
+}
+
+// Process ticks every active status, damage over time is dealt to hb and expired statuses are removed
+func (ss *Statuses) Process(ticks int, con core.Context, hb Damageable) {
+	active := ss.active[:0]
+	for _, s := range ss.active {
+		if !s.Process(ticks, con, hb, ss.Amplify) {
+			active = append(active, s)
+		}
+	}
+	ss.active = active
+}
+
+func (ss *Statuses) strongest(k core.Kind) int {
+	if s := ss.Status(k); s != nil {
+		return s.Strength()
+	}
+	return 0
+}
+
+func (ss *Statuses) Stunned() bool {
+	return ss.Status(StunStatus) != nil
+}
+
+// SpeedPercent is the percentage of its normal speed that an enemy moves at
+func (ss *Statuses) SpeedPercent() int {
+	if ss.Stunned() {
+		return 0
+	}
+	return core.MaxInt(0, 100-ss.strongest(SlowStatus))
+}
+
+func (ss *Statuses) Shred() int {
+	return ss.strongest(ShredStatus)
+}
+
+func (ss *Statuses) Vulnerability() int {
+	return ss.strongest(VulnerableStatus)
+}
+
+// Amplify scales incoming damage by any vulnerability
+func (ss *Statuses) Amplify(amount int) int {
+	return amount * (100 + ss.Vulnerability()) / 100
+}
+
+func (ss *Statuses) Draw(con *gg.Context, l core.Location) {
+	for _, s := range ss.active {
+		s.Draw(con, l, ss.tile)
+	}
+}
+
+func (ss *Statuses) Reset() {
+	ss.active = ss.active[:0]
+}
+
+func (ss *Statuses) Copy() *Statuses {
+	return NewStatuses(ss.immune, ss.assets, ss.tile)
+}
+
+func (ss *Statuses) State() []StatusState {
+	ret := make([]StatusState, len(ss.active))
+	for i, s := range ss.active {
+		ret[i] = s.State()
+	}
+	return ret
+}
+
+// Restore replaces the active statuses with previously saved ones
+func (ss *Statuses) Restore(states []StatusState) {
+	ss.Reset()
+	for _, st := range states {
+		st.check()
+		s := newStatus(st.StatusAttributes, ss.assets, ss.tile)
+		s.t = core.NewTicker(st.Total)
+		s.t.TickBy(st.Elapsed)
+		s.interval.TickBy(st.IntervalElapsed)
+		s.stacks = st.Stacks
+		ss.active = append(ss.active, s)
+	}
+}
+
+// check panics unless the state is of a status that is still running, a ticker restored at or past its max would
+// never be done
+func (st StatusState) check() {
+	switch {
+	case st.Total <= 0:
+		panic(fmt.Sprintf("status %s must last at least 1 tick, not %d", st.Kind, st.Total))
+	case st.Elapsed < 0 || st.Elapsed >= st.Total:
+		panic(fmt.Sprintf("status %s has run for %d of its %d ticks", st.Kind, st.Elapsed, st.Total))
+	case st.IntervalElapsed < 0 || st.IntervalElapsed >= core.MaxInt(1, st.Interval):
+		panic(fmt.Sprintf("status %s is %d ticks into an interval of %d", st.Kind, st.IntervalElapsed, st.Interval))
+	case st.Stacks < 1:
+		panic(fmt.Sprintf("status %s must have at least 1 stack, not %d", st.Kind, st.Stacks))
+	}
+}
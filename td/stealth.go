@@ -0,0 +1,111 @@
+package td
+
+import (
+	"tdgame/core"
+	"tdgame/graph"
+
+	"github.com/fogleman/gg"
+)
+
+type (
+	// DetectionGrid marks the tiles that are covered by a detection aura, it is recomputed at most once per
+	// tick, only after an aura has been added, so towers and enemies look up a tile instead of checking every
+	// detector
+	DetectionGrid struct {
+		g        graph.Graph
+		sources  []detectionSource
+		detected [][]bool
+		dirty    bool
+	}
+	detectionSource struct {
+		core.Point
+		radius int
+	}
+)
+
+const (
+	// DetectionKey is the context attribute of the detection grid
+	DetectionKey core.ContextKey = "detection"
+)
+
+var _ core.GameObject = (*DetectionGrid)(nil)
+
+func NewDetectionGrid(g graph.Graph) *DetectionGrid {
+	detected := make([][]bool, g.Height())
+	for i := range detected {
+		detected[i] = make([]bool, g.Width())
+	}
+	return &DetectionGrid{g, make([]detectionSource, 0), detected, false}
+}
+
+// AddSource adds a detection aura of radius pixels around center
+func (dg *DetectionGrid) AddSource(center core.Point, radius int) {
+	dg.sources = append(dg.sources, detectionSource{center, radius})
+	dg.dirty = true
+}
+
+func (dg *DetectionGrid) Process(ticks int, con core.Context) bool {
+	if con != nil {
+		con.SetAttribute(DetectionKey, dg)
+	}
+	if !dg.dirty {
+		return false
+	}
+	for _, row := range dg.detected {
+		for i := range row {
+			row[i] = false
+		}
+	}
+	size := dg.g.Spec().TileSize
+	for _, s := range dg.sources {
+		for x := 0; x < dg.g.Width(); x++ {
+			for y := 0; y < dg.g.Height(); y++ {
+				tile := core.Pt(x, y)
+				if tile.Scale(size).Center(size).Near(s.Point, s.radius) {
+					dg.detected[y][x] = true
+				}
+			}
+		}
+	}
+	dg.dirty = false
+	return false
+}
+
+// Detected reports whether the tile that p is in is covered by a detection aura
+func (dg *DetectionGrid) Detected(p core.Point) bool {
+	tile := p.TileIndex(dg.g.Spec().TileSize)
+	if tile.X() < 0 || tile.Y() < 0 || tile.Y() >= len(dg.detected) || tile.X() >= len(dg.detected[0]) {
+		return false
+	}
+	return dg.detected[tile.Y()][tile.X()]
+}
+
+func (dg *DetectionGrid) Draw(con *gg.Context) {
+	if !core.Grid {
+		return
+	}
+	con.SetRGBA(.6, .3, .9, .12)
+	size := dg.g.Spec().TileSize
+	for y, row := range dg.detected {
+		for x, d := range row {
+			if d {
+				con.DrawRectangle(core.Pt(x, y).Coordinates(size))
+			}
+		}
+	}
+	con.Fill()
+}
+
+// Detected reports whether p is covered by the detection grid of the context
+func Detected(con core.Context, p core.Point) bool {
+	if con == nil {
+		return false
+	}
+	dg, ok := con.Attribute(DetectionKey).(*DetectionGrid)
+	return ok && dg.Detected(p)
+}
+
+// Visible reports whether a tower can target e, detectors see stealthy enemies within their own range
+func Visible(ts *TowerSpec, e Enemy) bool {
+	return !e.Hidden() || ts.Detects
+}
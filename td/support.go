@@ -0,0 +1,134 @@
+package td
+
+import (
+	"image/color"
+	"tdgame/core"
+
+	"github.com/fogleman/gg"
+)
+
+type (
+	SupportAttributes struct {
+		SupportRadius int `yaml:"radius"` // pixels from the center of the enemy that allies are supported in
+		Amount        int // health healed or shield projected
+		Cooldown      int // ticks between each pulse
+	}
+	// HealerEnemy heals every ally within its radius once per cooldown
+	HealerEnemy struct {
+		*BasicEnemy
+		t *core.Ticker
+	}
+	// ShieldEnemy projects a shield that absorbs damage onto every ally within its radius once per cooldown
+	ShieldEnemy struct {
+		*BasicEnemy
+		t *core.Ticker
+	}
+	// Pulse is a ring that grows out to a radius and fades away
+	Pulse struct {
+		*core.LocationWrapper
+		radius int
+		c      color.RGBA
+		t      *core.Ticker
+	}
+)
+
+const (
+	PulseLength = 16
+)
+
+var (
+	_           Enemy = (*HealerEnemy)(nil)
+	_           Enemy = (*ShieldEnemy)(nil)
+	HealColor         = color.RGBA{80, 255, 120, 200}
+	ShieldColor       = color.RGBA{80, 180, 255, 200}
+)
+
+// allies are the enemies in play within radius of e, not including e
+func allies(e *BasicEnemy, radius int) []Enemy {
+	center := e.Center()
+	ret := make([]Enemy, 0)
+	for _, d := range e.Graph().DamageablesWithin(center, radius) {
+		if a, ok := d.(Enemy); ok && a != e.self && InPlay(a) {
+			ret = append(ret, a)
+		}
+	}
+	return ret
+}
+
+// support runs f on the allies of e when the cooldown is up and shows a pulse if any were supported
+func support(e *BasicEnemy, t *core.Ticker, con core.Context, c color.RGBA, f func(a Enemy)) {
+	if !t.Tick() {
+		return
+	}
+	t.Reset()
+	as := allies(e, e.SupportRadius)
+	for _, a := range as {
+		f(a)
+	}
+	if len(as) > 0 && con != nil {
+		con.Add(core.EffectLayer, NewPulse(core.Loc(e.Center(), 0), e.SupportRadius, c))
+	}
+}
+
+func (h *HealerEnemy) Process(ticks int, con core.Context) bool {
+	if !h.Destroyed() && !h.Done() {
+		support(h.BasicEnemy, h.t, con, HealColor, func(a Enemy) {
+			a.Heal(h.Amount)
+		})
+	}
+	return h.BasicEnemy.Process(ticks, con)
+}
+
+func (h *HealerEnemy) Reset() {
+	h.BasicEnemy.Reset()
+	h.t.Reset()
+}
+
+func (h *HealerEnemy) CopyAt(l core.Location) Enemy {
+	be := h.BasicEnemy.copy()
+	ret := &HealerEnemy{be, core.NewTicker(h.t.Max())}
+	be.self = ret
+	be.TileLocation.Move(l, ret)
+	return ret
+}
+
+func (s *ShieldEnemy) Process(ticks int, con core.Context) bool {
+	if !s.Destroyed() && !s.Done() {
+		support(s.BasicEnemy, s.t, con, ShieldColor, func(a Enemy) {
+			if sh, ok := a.(Shieldable); ok {
+				sh.Shield(s.Amount)
+			}
+		})
+	}
+	return s.BasicEnemy.Process(ticks, con)
+}
+
+func (s *ShieldEnemy) Reset() {
+	s.BasicEnemy.Reset()
+	s.t.Reset()
+}
+
+func (s *ShieldEnemy) CopyAt(l core.Location) Enemy {
+	be := s.BasicEnemy.copy()
+	ret := &ShieldEnemy{be, core.NewTicker(s.t.Max())}
+	be.self = ret
+	be.TileLocation.Move(l, ret)
+	return ret
+}
+
+func NewPulse(center core.Location, radius int, c color.RGBA) *Pulse {
+	return &Pulse{core.LocWrapper(center), radius, c, core.NewTicker(PulseLength)}
+}
+
+func (p *Pulse) Process(ticks int, con core.Context) bool {
+	return p.t.Tick()
+}
+
+func (p *Pulse) Draw(con *gg.Context) {
+	progress := float64(p.t.Ticks()) / PulseLength
+	c := p.Location()
+	con.SetRGBA255(int(p.c.R), int(p.c.G), int(p.c.B), int(float64(p.c.A)*(1-progress)))
+	con.SetLineWidth(3)
+	con.DrawCircle(float64(c.X()), float64(c.Y()), float64(p.radius)*progress)
+	con.Stroke()
+}
@@ -1,7 +1,6 @@
 package td
 
 import (
-	"fmt"
 	"image/color"
 	"tdgame/animator"
 	"tdgame/asset"
@@ -14,10 +13,14 @@ import (
 type (
 	TowerAttributes struct {
 		ProjectileAttributes `yaml:"projectile"`
+		BeamAttributes       `yaml:"beam"`
 		Asset                core.Kind
 		core.Range           // min, max ticks for projectile to reach enemy; min*speed, max*speed pixels donut radii
 		Delay                int
 		Cost                 int
+		Targets              core.Kind // ground, air or both
+		Detects              bool      // the tower can target stealthy enemies in its range
+		DetectionAura        int       `yaml:"detectionAura"` // tiles around the tower where every tower can target stealthy enemies
 	}
 	TowerSpec struct {
 		core.Meta
@@ -25,6 +28,9 @@ type (
 	}
 	TowerAtlas struct {
 		tows   map[core.Kind]Tower
+		pools  map[core.Kind]*core.Pool[Projectile] // projectile pools by tower name
+		dg     *DetectionGrid
+		cg     *CoverageGrid
 		assets asset.AssetAtlas
 		anims  animator.AnimatorAtlas
 		graphs graph.GraphAtlas
@@ -36,22 +42,29 @@ type (
 		core.Drawer
 		Spawn(enemies *ParticleList) Particle
 		CopyAt(loc core.Location, ta *TowerAtlas) Tower
+		// Reach is the farthest in pixels from the center of the tower that it can hit an enemy, terrain included
+		Reach() int
 	}
 	ShootingTower struct {
 		*TowerSpec
 		*core.LocationWrapper
-		nodes []*graph.Node
-		// TODO: ProjectilePool
+		g        graph.Graph
+		nodes    []*graph.Node
+		pool     *core.Pool[Projectile]
 		enemyLoc *core.Location
 		sprite   *asset.Sprite
 		t        *core.Ticker
-		proj     Projectile
+		proj     Projectile // prototype that the projectile pool copies
+		disabled bool
+		rng      core.Range // the range of the spec stretched by the terrain under the tower
+		damage   int        // percent of extra damage from the terrain under the tower
 	}
 )
 
 const (
 	TowerType       = "tower"
 	ShootingVariety = "shooting"
+	BeamVariety     = "beam"
 )
 
 func (p ProjectileAttributes) Kind() core.Kind {
@@ -66,12 +79,66 @@ var _ core.DeclarationHandler = (*TowerAtlas)(nil)
 
 func NewTowerAtlas() *TowerAtlas {
 	return &TowerAtlas{
-		tows: make(map[core.Kind]Tower),
+		tows:  make(map[core.Kind]Tower),
+		pools: make(map[core.Kind]*core.Pool[Projectile]),
 	}
 }
 
 func (ta *TowerAtlas) Tower(l core.Location, k core.Kind) Tower {
-	return ta.tows[k].CopyAt(l, ta)
+	ret, tile := ta.tows[k].CopyAt(l, ta), ta.graphs.Graph("map").Spec().TileSize
+	if aura := ret.Spec().DetectionAura; aura > 0 {
+		ta.Detection().AddSource(l.Center(tile), aura*tile)
+	}
+	ta.Coverage().AddTower(l.Center(tile), ret.Reach())
+	return ret
+}
+
+// Place creates a tower like Tower if the tile under it is buildable, on a maze map the tile is blocked first
+// and the tower is not created when that would leave enemies without a way out
+func (ta *TowerAtlas) Place(l core.Location, k core.Kind) (Tower, bool) {
+	if g, ok := ta.graphs.Graph("map").(graph.CachedImageGraph); ok {
+		tile := l.Center(g.TileSize).TileIndex(g.TileSize)
+		if nd := g.Node(tile); nd == nil || !nd.Buildable() {
+			return nil, false
+		}
+		if g.Maze() != nil && !g.Maze().Block(tile) {
+			return nil, false
+		}
+	}
+	return ta.Tower(l, k), true
+}
+
+// Coverage is the grid of how many towers created by the atlas reach each tile
+func (ta *TowerAtlas) Coverage() *CoverageGrid {
+	if ta.cg == nil {
+		ta.cg = NewCoverageGrid(ta.graphs.Graph("map"))
+	}
+	return ta.cg
+}
+
+// Reach is the farthest in pixels from the center of the tower that it can hit an enemy on a map with tiles
+// tile pixels wide
+func (ts *TowerSpec) Reach(tile int) int {
+	if ts.Variety == BeamVariety {
+		return ts.Max * tile
+	}
+	return ts.Max * ts.Speed
+}
+
+// Detection is the grid of tiles covered by the detection auras of the towers created by the atlas
+func (ta *TowerAtlas) Detection() *DetectionGrid {
+	if ta.dg == nil {
+		ta.dg = NewDetectionGrid(ta.graphs.Graph("map"))
+	}
+	return ta.dg
+}
+
+func (ta *TowerAtlas) Pool(k core.Kind) *core.Pool[Projectile] {
+	return ta.pools[k]
+}
+
+func (ta *TowerAtlas) Pools() map[core.Kind]*core.Pool[Projectile] {
+	return ta.pools
 }
 
 func (ta *TowerAtlas) AddTower(t Tower) {
@@ -84,7 +151,7 @@ func (ta *TowerAtlas) Type() core.Kind {
 
 func (ta *TowerAtlas) Match(pm *core.PreMeta) (spec core.Kinder, priority int) {
 	switch pm.Variety {
-	case ShootingVariety:
+	case ShootingVariety, BeamVariety:
 		return &TowerSpec{}, 5
 	default:
 		panic("variety of tower does not exist")
@@ -98,11 +165,16 @@ func (ta *TowerAtlas) PreLoad(d *core.Declarations) {
 }
 
 func (ta *TowerAtlas) Load(spec core.Kinder, d *core.Declarations) {
-	fmt.Println(ta.graphs)
 	graph := ta.graphs.Graph("map").(graph.CachedImageGraph)
 	switch ts := spec.(type) {
 	case *TowerSpec:
-		ta.tows[ts.Name] = TowerFromSpec(ts, ta.assets, ta.anims, graph)
+		t := TowerFromSpec(ts, ta.assets, ta.anims, graph)
+		ta.tows[ts.Name] = t
+		if st, ok := t.(*ShootingTower); ok {
+			ta.pools[ts.Name] = core.NewPool(ts.PoolSize, func() Projectile {
+				return st.proj.CopyAt(core.ZeroLoc, graph)
+			}).SetMax(ts.PoolMax).SetShrink(core.ShrinkToPeak(ts.PoolSize))
+		}
 	default:
 		panic("variety of tower does not exist")
 	}
@@ -110,29 +182,52 @@ func (ta *TowerAtlas) Load(spec core.Kinder, d *core.Declarations) {
 
 var _ core.DeclarationHandler = (*TowerAtlas)(nil)
 var _ Tower = (*ShootingTower)(nil)
+var _ Tower = (*BeamTower)(nil)
 
 func TowerFromSpec(ts *TowerSpec, assets asset.AssetAtlas, anims animator.AnimatorAtlas, g graph.CachedImageGraph) Tower {
 	switch ts.Variety {
 	case "shooting":
 		projAsset := assets.Asset(ts.ProjectileAttributes.Asset)
-		proj := NewBullet(
+		projAsset.SetTileSize(g.TileSize)
+		proj := NewProjectile(
 			&ts.ProjectileAttributes,
 			projAsset,
 			g.TLoc(projAsset.Offset(), projAsset.Size()),
-			nil,
-			asset.NewSpriteEffect(
+			ts.Targets,
+			ts.Name,
+			asset.NewEffectPool(ts.PoolSize, asset.NewSpriteEffect(
 				core.ZeroLoc,
-				assets.Sprite(ts.ProjectileAttributes.Effect),
-			),
+				sprite(assets, ts.ProjectileAttributes.Effect, g.TileSize),
+			)),
 		)
 		return &ShootingTower{
 			ts,
 			core.LocWrapper(core.ZeroLoc),
+			g,
+			nil,
 			nil,
 			nil,
-			assets.Sprite(ts.Asset),
+			sprite(assets, ts.Asset, g.TileSize),
 			core.NewTicker(ts.Delay),
 			proj,
+			false,
+			ts.Range,
+			0,
+		}
+	case "beam":
+		return &BeamTower{
+			ts,
+			core.LocWrapper(core.ZeroLoc),
+			g,
+			nil,
+			sprite(assets, ts.Asset, g.TileSize),
+			core.NewTicker(core.MaxInt(1, ts.Delay)),
+			nil,
+			nil,
+			0,
+			false,
+			0,
+			0,
 		}
 	default:
 		panic("variety of tower does not exist")
@@ -140,42 +235,70 @@ func TowerFromSpec(ts *TowerSpec, assets asset.AssetAtlas, anims animator.Animat
 }
 
 func (t *ShootingTower) Process(ticks int, con core.Context) bool {
-	// if !t.Done() {
-	// 	if t.enemyLoc != nil {
-	// 		t.sprite.Process(ticks, con)
-	// 		if t.t.Ticks() == t.sprite.Length()-1 {
-	// 			t.enemyLoc = nil
-	// 		}
-	// 	}
-	// 	t.t.Tick()
-	// }
+	if t.disabled = Disrupted(con, t.Center(), ticks); t.disabled {
+		return false
+	}
+	if t.enemyLoc != nil {
+		// play the firing animation once
+		t.sprite.Process(ticks, con)
+		if t.t.Ticks() >= t.sprite.Length()-1 {
+			t.enemyLoc = nil
+			t.sprite.Reset()
+		}
+	}
+	if !t.t.Done() {
+		t.t.Tick()
+		return false
+	}
+	for _, nd := range t.nodes {
+		for _, d := range nd.Damageables() {
+			e, ok := d.(Enemy)
+			if !ok || !InPlay(e) || !CanTarget(t.Targets, e) || !Visible(t.TowerSpec, e) {
+				continue
+			}
+			if proj := t.calculateTrajectory(e); proj != nil {
+				t.t.Reset()
+				if con != nil {
+					con.Add(core.ProjectileLayer, proj)
+				}
+				return false
+			}
+		}
+	}
 	return false
 }
 
+// Intercept finds where a projectile fired from a point at speed pixels per tick meets e, taking between
+// rng.Min and rng.Max ticks to get there
+func Intercept(from core.Point, e Enemy, rng core.Range, speed int) (core.Point, int, bool) {
+	for i := core.MaxInt(1, rng.Min); i <= rng.Max; i++ {
+		eLoc, ok := e.LocationAt(i)
+		if !ok {
+			break
+		}
+		if eLoc.Near(from, i*speed) {
+			return eLoc.Point, i, true
+		}
+	}
+	return core.ZeroPt, 0, false
+}
+
 func (t *ShootingTower) calculateTrajectory(e Enemy) Projectile {
-	// fmt.Println("\tstart")
-	// tPoint := t.Location().Point
-	// // if enemy is within range
-	// if e.Location().DistanceSquared(tPoint) >= util.Square(t.Max*t.Speed) {
-	// 	return nil
-	// }
-	// // fmt.Println("\tclose enough")
-	// // find tick to hit the enemy
-	// for i := t.Min; i <= t.Max; i++ { // i += t.ExplosionRadius {
-	// 	// fmt.Println("\t\t", i)
-	// 	eLoc, ok := e.LocationAt(i)
-	// 	if !ok {
-	// 		return nil
-	// 	}
-	// 	if eLoc.Near(tPoint, util.Square(i*t.Speed)) {
-	// 		// fmt.Println(t.proj, t.Location())
-	// 		proj := t.proj.CopyAt(t.Location())
-	// 		proj.UpdateTarget(animator.AnimatorFromLine(tPoint, eLoc.Point, i), e)
-	// 		return proj
-	// 	}
-	// }
-	// fmt.Println("\tfinished for")
-	return nil
+	tPoint := t.Location().Point
+	at, ticks, ok := Intercept(tPoint, e, t.rng, t.Speed)
+	if !ok {
+		return nil
+	}
+	proj, ok := t.pool.TryItem()
+	if !ok {
+		// every projectile is in the air, hold fire until one comes back
+		return nil
+	}
+	loc := core.Loc(at, 0)
+	t.enemyLoc = &loc
+	proj.Fire(tPoint, e, at, ticks)
+	proj.Boost(t.damage)
+	return proj
 }
 
 func (t *ShootingTower) Spawn(pl *ParticleList) Particle {
@@ -192,31 +315,71 @@ func (t *ShootingTower) Spawn(pl *ParticleList) Particle {
 func (t *ShootingTower) Draw(con *gg.Context) {
 	// draw circle radius of test tower
 	if core.Grid {
-		centered := t.Location().Add(core.Pt(32, 32))
+		centered := t.Center()
 		con.SetColor(color.Black)
-		con.DrawCircle(float64(centered.X()), float64(centered.Y()), float64(t.Max*t.Speed))
+		con.DrawCircle(float64(centered.X()), float64(centered.Y()), float64(t.Reach()))
 		con.Stroke()
 		con.SetRGBA(.9, .9, .9, 0.2)
-		con.DrawCircle(float64(centered.X()), float64(centered.Y()), float64(t.Max*t.Speed))
+		con.DrawCircle(float64(centered.X()), float64(centered.Y()), float64(t.Reach()))
 		con.Fill()
 	}
 	t.sprite.Draw(con, t.Location())
+	drawDisabled(con, t.Location(), t.g.Spec().TileSize, t.disabled)
+}
+
+// drawDisabled greys out the tile of a tower that has been disabled
+func drawDisabled(con *gg.Context, l core.Location, tile int, disabled bool) {
+	if !disabled {
+		return
+	}
+	con.SetRGBA(.2, .2, .2, .6)
+	con.DrawRectangle(float64(l.X()), float64(l.Y()), float64(tile), float64(tile))
+	con.Fill()
 }
 
 func (t *ShootingTower) CopyAt(l core.Location, ta *TowerAtlas) Tower {
 	ter := core.NewTicker(t.Delay)
 	ter.TickBy(t.Delay)
+	g := ta.graphs.Graph("map").(graph.CachedImageGraph)
+	mods := g.ModifiersAt(l.Center(g.TileSize))
+	rng := boost(t.Range, mods.Range)
 	return &ShootingTower{
 		t.TowerSpec,
 		core.LocWrapper(l),
-		ta.graphs.Graph("map").(graph.CachedImageGraph).TilesAround(l.Point, t.Range),
+		g,
+		// the range is in ticks of projectile travel so gather every tile it can reach
+		g.TilesAround(l.Point, core.Range{Min: 0, Max: rng.Max*t.Speed/g.TileSize + 2}),
+		ta.Pool(t.Name),
 		nil,
 		t.sprite.Copy().(*asset.Sprite),
 		ter,
-		t.proj.CopyAt(l, ta.graphs.Graph("map")),
+		t.proj,
+		false,
+		rng,
+		mods.Damage,
 	}
 }
 
+// sprite is a copy of sprite k centered in tiles tile pixels wide
+func sprite(assets asset.AssetAtlas, k core.Kind, tile int) *asset.Sprite {
+	ret := assets.Sprite(k)
+	ret.SetTileSize(tile)
+	return ret
+}
+
+// boost stretches the max of a range by a percent
+func boost(rng core.Range, percent int) core.Range {
+	return core.Range{Min: rng.Min, Max: rng.Max * (100 + percent) / 100}
+}
+
+func (t *ShootingTower) Center() core.Point {
+	return t.Location().Center(t.g.Spec().TileSize)
+}
+
+func (t *ShootingTower) Reach() int {
+	return t.rng.Max * t.Speed
+}
+
 func (t *ShootingTower) Spec() *TowerSpec {
 	return t.TowerSpec
 }